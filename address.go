@@ -0,0 +1,144 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultPort is the port used when an address does not include one.
+const defaultPort = "443"
+
+// tlsSchemePorts maps URL schemes which use TLS to their default port.
+var tlsSchemePorts = map[string]string{
+	"https": "443",
+	"wss":   "443",
+	"ldaps": "636",
+	"smtps": "465",
+	"imaps": "993",
+	"pop3s": "995",
+}
+
+// FetchChainURL will fetch the certificate chain of the remote system identified by a URL such as
+// https://example.com/path. The port defaults to the scheme's standard port, 443 for https, when not present in the
+// URL. Schemes which do not use TLS, such as http, are rejected.
+func FetchChainURL(rawurl string, opts ...Option) ([]*CertificateStatus, error) {
+	return FetchChainURLContext(context.Background(), rawurl, opts...)
+}
+
+// FetchChainURLContext is the same as FetchChainURL but uses the provided context to cancel or set a deadline on the connection and TLS handshake.
+func FetchChainURLContext(ctx context.Context, rawurl string, opts ...Option) ([]*CertificateStatus, error) {
+	address, err := addressFromURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return FetchChainContext(ctx, address, opts...)
+}
+
+// addressFromURL will extract a host:port address from a URL, using the scheme's default port when one is not specified.
+func addressFromURL(rawurl string) (string, error) {
+	host, port, err := hostPortFromURL(rawurl)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// hostPortFromURL will extract the host and port from a URL, using the scheme's default port when one is not specified.
+func hostPortFromURL(rawurl string) (string, string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid URL %s - %s", rawurl, err)
+	}
+	port, ok := tlsSchemePorts[strings.ToLower(u.Scheme)]
+	if !ok {
+		return "", "", fmt.Errorf("Unsupported URL scheme %q in %s, only TLS schemes such as https are supported", u.Scheme, rawurl)
+	}
+	if u.Hostname() == "" {
+		return "", "", fmt.Errorf("Invalid URL %s - missing host", rawurl)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return u.Hostname(), port, nil
+}
+
+// NormalizeAddress will split any of the address forms accepted by this package into a host and port. Accepted forms
+// include a bare host such as example.com, a host:port such as example.com:8443, and a URL such as
+// https://example.com:8443/path. IPv6 literals may be bare or bracketed, and any zone identifier is retained. Paths,
+// queries, and fragments are ignored, including on addresses without a scheme such as example.com/path. The port
+// defaults to 443, or the scheme's standard port for URLs, and the returned host never includes brackets. An error is
+// returned for empty hosts, invalid ports, URL schemes which do not use TLS, and Unix domain socket addresses.
+//
+// Every function accepting an address uses NormalizeAddress to determine where to connect and which hostname to send
+// as SNI.
+func NormalizeAddress(input string) (host, port string, err error) {
+	address := strings.TrimSpace(input)
+	if _, ok := unixSocketPath(address); ok {
+		return "", "", fmt.Errorf("Unix domain socket address %s has no host or port", input)
+	}
+	if strings.Contains(address, "://") {
+		return hostPortFromURL(address)
+	}
+
+	// drop any trailing path, query, or fragment
+	if i := strings.IndexAny(address, "/?#"); i >= 0 {
+		address = address[:i]
+	}
+
+	host, port, err = net.SplitHostPort(address)
+	if err != nil {
+		// not in host:port form, such as a bare hostname or IPv6 literal
+		host = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+		port = ""
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("Invalid address %q - missing host", input)
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return "", "", fmt.Errorf("Invalid address %q - invalid port %q", input, port)
+	}
+	return host, port, nil
+}
+
+// unixSocketPath will return the socket path from an address of the form unix:/path/to.sock.
+func unixSocketPath(address string) (string, bool) {
+	return strings.CutPrefix(address, "unix:")
+}
+
+// hostFromAddress will return the host portion of an address with any IPv6 brackets and zone identifier removed.
+// Addresses are parsed with NormalizeAddress, so URLs and bare IPv6 literals are also accepted. Unix domain socket
+// addresses and invalid addresses have no host and return an empty string.
+func hostFromAddress(address string) string {
+	host, _, err := NormalizeAddress(address)
+	if err != nil {
+		return ""
+	}
+	host, _, _ = strings.Cut(host, "%")
+	return host
+}
+
+// serverName will derive the SNI hostname from the provided address, returning an empty string for IP literals.
+func serverName(address string) string {
+	host := hostFromAddress(address)
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	return host
+}
+
+// normalizeAddress will convert an address into the bracketed host:port form used to connect, appending the default
+// port when one is not specified. See NormalizeAddress for the accepted forms.
+func normalizeAddress(address string) (string, error) {
+	host, port, err := NormalizeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,277 @@
+package hazexpired
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test extracting addresses from URLs
+func TestAddressFromURL(t *testing.T) {
+	tt := []struct {
+		url     string
+		address string
+		err     bool
+	}{
+		{"https://example.com", "example.com:443", false},
+		{"https://example.com/path?q=1", "example.com:443", false},
+		{"HTTPS://example.com:8443/path", "example.com:8443", false},
+		{"https://[2606:4700:4700::1111]/", "[2606:4700:4700::1111]:443", false},
+		{"ldaps://ldap.example.com", "ldap.example.com:636", false},
+		{"http://example.com", "", true},
+		{"example.com:443", "", true},
+		{"https:///path", "", true},
+		{"https://exa mple.com", "", true},
+	}
+
+	for _, c := range tt {
+		t.Run(c.url, func(t *testing.T) {
+			address, err := addressFromURL(c.url)
+			if c.err && err == nil {
+				t.Errorf("Expected failure when parsing %s, err is nil", c.url)
+			}
+			if !c.err && err != nil {
+				t.Errorf("Unexpected failure when parsing %s - %s", c.url, err)
+			}
+			if address != c.address {
+				t.Errorf("Unexpected address from %s, expected %s got %s", c.url, c.address, address)
+			}
+		})
+	}
+}
+
+// Test fetching certificates using a URL
+func TestFetchChainURL(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	chain, err := FetchChainURL("https://127.0.0.1:9000/healthz")
+	if err != nil {
+		t.Fatalf("Unexpected failure when fetching Certificate Chain by URL - %s", err)
+	}
+	if len(chain) == 0 {
+		t.Errorf("Unexpected empty Certificate Chain fetched by URL")
+	}
+
+	_, err = FetchChainURL("http://127.0.0.1:9000/healthz")
+	if err == nil {
+		t.Errorf("Expected failure when fetching a non-TLS URL, err is nil")
+	}
+}
+
+// Test host and SNI extraction for hostnames and IP literals
+func TestHostFromAddress(t *testing.T) {
+	tt := []struct {
+		address    string
+		host       string
+		serverName string
+	}{
+		{"example.com:443", "example.com", "example.com"},
+		{"example.com", "example.com", "example.com"},
+		{"127.0.0.1:443", "127.0.0.1", ""},
+		{"[2606:4700:4700::1111]:443", "2606:4700:4700::1111", ""},
+		{"[fe80::1%eth0]:443", "fe80::1", ""},
+		{"[2606:4700:4700::1111]", "2606:4700:4700::1111", ""},
+		{"2606:4700:4700::1111", "2606:4700:4700::1111", ""},
+		{"fe80::1%eth0", "fe80::1", ""},
+		{"unix:/run/app.sock", "", ""},
+		{"https://example.com:8443/path", "example.com", "example.com"},
+		{"https://[2606:4700:4700::1111]/path", "2606:4700:4700::1111", ""},
+		{"example.com/path", "example.com", "example.com"},
+	}
+
+	for _, c := range tt {
+		t.Run(c.address, func(t *testing.T) {
+			if v := hostFromAddress(c.address); v != c.host {
+				t.Errorf("Unexpected host from %s, expected %s got %s", c.address, c.host, v)
+			}
+			if v := serverName(c.address); v != c.serverName {
+				t.Errorf("Unexpected server name from %s, expected %q got %q", c.address, c.serverName, v)
+			}
+		})
+	}
+}
+
+// Test fetching certificates from a bracketed IPv6 address
+func TestIPv6Address(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	// Skip when the host has no IPv6 loopback
+	conn, err := net.Dial("tcp", "[::1]:9000")
+	if err != nil {
+		t.Skipf("IPv6 loopback is unavailable - %s", err)
+	}
+	conn.Close()
+
+	chain, err := FetchChain("[::1]:9000")
+	if err != nil {
+		t.Fatalf("Unexpected failure when fetching Certificate Chain from IPv6 address - %s", err)
+	}
+	if len(chain) == 0 {
+		t.Errorf("Unexpected empty Certificate Chain fetched from IPv6 address")
+	}
+}
+
+// Test appending the default port to addresses without one
+func TestNormalizeAddress(t *testing.T) {
+	tt := []struct {
+		address    string
+		normalized string
+	}{
+		{"example.com", "example.com:443"},
+		{"example.com:8443", "example.com:8443"},
+		{"127.0.0.1", "127.0.0.1:443"},
+		{"127.0.0.1:9000", "127.0.0.1:9000"},
+		{"2606:4700:4700::1111", "[2606:4700:4700::1111]:443"},
+		{"[2606:4700:4700::1111]", "[2606:4700:4700::1111]:443"},
+		{"[2606:4700:4700::1111]:8443", "[2606:4700:4700::1111]:8443"},
+		{"fe80::1%eth0", "[fe80::1%eth0]:443"},
+		{"[fe80::1%eth0]:8443", "[fe80::1%eth0]:8443"},
+	}
+
+	for _, c := range tt {
+		t.Run(c.address, func(t *testing.T) {
+			v, err := normalizeAddress(c.address)
+			if err != nil {
+				t.Fatalf("Unexpected failure when normalizing %s - %s", c.address, err)
+			}
+			if v != c.normalized {
+				t.Errorf("Unexpected normalized address from %s, expected %s got %s", c.address, c.normalized, v)
+			}
+		})
+	}
+}
+
+// Test splitting the accepted address forms into a host and port
+func TestNormalizeAddressForms(t *testing.T) {
+	tt := []struct {
+		input string
+		host  string
+		port  string
+		err   bool
+	}{
+		{"example.com", "example.com", "443", false},
+		{"example.com:8443", "example.com", "8443", false},
+		{" example.com ", "example.com", "443", false},
+		{"example.com:", "example.com", "443", false},
+		{"example.com/path", "example.com", "443", false},
+		{"example.com:8443/path/to?q=1#frag", "example.com", "8443", false},
+		{"example.com?q=1", "example.com", "443", false},
+		{"https://example.com", "example.com", "443", false},
+		{"https://example.com/", "example.com", "443", false},
+		{"https://example.com:8443/path", "example.com", "8443", false},
+		{"HTTPS://Example.com:8443/path?q=1", "Example.com", "8443", false},
+		{"ldaps://ldap.example.com/dc=example", "ldap.example.com", "636", false},
+		{"127.0.0.1", "127.0.0.1", "443", false},
+		{"127.0.0.1:9000/path", "127.0.0.1", "9000", false},
+
+		// IPv6
+		{"::1", "::1", "443", false},
+		{"2606:4700:4700::1111", "2606:4700:4700::1111", "443", false},
+		{"[2606:4700:4700::1111]", "2606:4700:4700::1111", "443", false},
+		{"[2606:4700:4700::1111]:8443", "2606:4700:4700::1111", "8443", false},
+		{"[2606:4700:4700::1111]/path", "2606:4700:4700::1111", "443", false},
+		{"[2606:4700:4700::1111]:8443/path", "2606:4700:4700::1111", "8443", false},
+		{"https://[2606:4700:4700::1111]", "2606:4700:4700::1111", "443", false},
+		{"https://[2606:4700:4700::1111]:8443/path?q=1", "2606:4700:4700::1111", "8443", false},
+		{"fe80::1%eth0", "fe80::1%eth0", "443", false},
+		{"[fe80::1%eth0]:8443/path", "fe80::1%eth0", "8443", false},
+
+		// Invalid
+		{"", "", "", true},
+		{"/path", "", "", true},
+		{":443", "", "", true},
+		{"[]:443", "", "", true},
+		{"example.com:https", "", "", true},
+		{"example.com:0", "", "", true},
+		{"example.com:65536", "", "", true},
+		{"http://example.com", "", "", true},
+		{"https:///path", "", "", true},
+		{"unix:/run/app.sock", "", "", true},
+	}
+
+	for _, c := range tt {
+		t.Run(c.input, func(t *testing.T) {
+			host, port, err := NormalizeAddress(c.input)
+			if c.err && err == nil {
+				t.Errorf("Expected failure when normalizing %q, err is nil", c.input)
+			}
+			if !c.err && err != nil {
+				t.Errorf("Unexpected failure when normalizing %q - %s", c.input, err)
+			}
+			if host != c.host || port != c.port {
+				t.Errorf("Unexpected host and port from %q, expected %s %s got %s %s", c.input, c.host, c.port, host, port)
+			}
+		})
+	}
+}
+
+// Test fetching certificates with each of the accepted address forms
+func TestAddressForms(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	for _, address := range []string{"127.0.0.1:9000", "127.0.0.1:9000/healthz", "https://127.0.0.1:9000/healthz"} {
+		t.Run(address, func(t *testing.T) {
+			chain, err := FetchChain(address)
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+			}
+			if len(chain) == 0 {
+				t.Errorf("Unexpected empty Certificate Chain")
+			}
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := FetchChain("http://127.0.0.1:9000")
+		if err == nil {
+			t.Fatalf("Expected failure when fetching a non-TLS URL, err is nil")
+		}
+		var dialErr *DialError
+		if errors.As(err, &dialErr) || retryable(err) {
+			t.Errorf("Expected an invalid address not to be a retryable DialError, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchResult holds the outcome of checking a single address as part of a batch.
+type BatchResult struct {
+	// Expired indicates if there is an expired certificate within the chain, this is true when Err is set
+	Expired bool
+
+	// Chain is the fetched certificate chain
+	Chain []*CertificateStatus
+
+	// Err is any error encountered while fetching the certificate chain for this address
+	Err error
+}
+
+// ExpiredBatch will check many remote systems concurrently and return a BatchResult for each address. A failure to
+// fetch an individual address is recorded within that address's BatchResult rather than failing the whole batch. The
+// number of simultaneous connections can be controlled with WithConcurrency.
+func ExpiredBatch(addresses []string, opts ...Option) (map[string]BatchResult, error) {
+	return ExpiredBatchContext(context.Background(), addresses, opts...)
+}
+
+// ExpiredBatchContext is the same as ExpiredBatch but uses the provided context to cancel or set a deadline on fetching the certificate chains.
+func ExpiredBatchContext(ctx context.Context, addresses []string, opts ...Option) (map[string]BatchResult, error) {
+	cfg := newConfig(opts...)
+	if cfg.concurrency < 1 {
+		return nil, fmt.Errorf("Batch concurrency must be at least 1, got %d", cfg.concurrency)
+	}
+
+	results := make(map[string]BatchResult, len(addresses))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Start a bounded pool of workers to fetch each address
+	queue := make(chan string)
+	for i := 0; i < cfg.concurrency && i < len(addresses); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for address := range queue {
+				r := BatchResult{}
+				r.Chain, r.Err = fetchChain(ctx, address, opts...)
+				r.Expired = r.Err != nil || anyExpired(r.Chain)
+				mu.Lock()
+				results[address] = r
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Queue each unique address
+	seen := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		queue <- address
+	}
+	close(queue)
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,48 @@
+package hazexpired
+
+import (
+	"testing"
+	"time"
+)
+
+// Test checking a mix of reachable and unreachable addresses in one batch
+func TestExpiredBatch(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Truncate(24 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("MixedResults", func(t *testing.T) {
+		results, err := ExpiredBatch([]string{"127.0.0.1:9000", "iamateapot:418", "127.0.0.1:9000"}, WithConcurrency(2))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling ExpiredBatch - %s", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Unexpected number of results, expected 2 got %d", len(results))
+		}
+		if r := results["127.0.0.1:9000"]; r.Err != nil || !r.Expired || len(r.Chain) == 0 {
+			t.Errorf("Unexpected result for expired certificate - %+v", r)
+		}
+		if r := results["iamateapot:418"]; r.Err == nil || !r.Expired {
+			t.Errorf("Expected failure result for invalid address - %+v", r)
+		}
+	})
+
+	t.Run("InvalidConcurrency", func(t *testing.T) {
+		_, err := ExpiredBatch([]string{"127.0.0.1:9000"}, WithConcurrency(0))
+		if err == nil {
+			t.Errorf("Expected failure when calling with a concurrency of 0, err is nil")
+		}
+	})
+}
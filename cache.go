@@ -0,0 +1,206 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache memoizes fetched certificate chains per address for a configurable TTL, reducing redundant TLS handshakes
+// when the same remote system is checked repeatedly within a short time. A Cache is safe for concurrent use,
+// concurrent checks of an address which is not cached share a single fetch, and each caller receives its own copy of
+// the chain. Failed fetches are not cached, and results reflect the time the chain was fetched. Expired chains are
+// removed whenever a check misses the cache.
+//
+//	cache := hazexpired.NewCache(30 * time.Second)
+//	check, err := cache.Expired("example.com:443")
+type Cache struct {
+	// mu guards entries and inflight
+	mu sync.Mutex
+
+	// ttl is how long a fetched chain is reused
+	ttl time.Duration
+
+	// opts are the options used when fetching each certificate chain
+	opts []Option
+
+	// entries are the cached chains keyed by address
+	entries map[string]cacheEntry
+
+	// inflight are the fetches in progress keyed by address, shared by concurrent checks
+	inflight map[string]*cacheCall
+}
+
+// cacheEntry is a cached certificate chain and the time it should no longer be used.
+type cacheEntry struct {
+	chain   []*CertificateStatus
+	expires time.Time
+}
+
+// cacheCall is a fetch in progress, done is closed once chain and err are set.
+type cacheCall struct {
+	done  chan struct{}
+	chain []*CertificateStatus
+	err   error
+}
+
+// NewCache will create a Cache which reuses fetched certificate chains for the TTL, the options are used for each fetch.
+func NewCache(ttl time.Duration, opts ...Option) *Cache {
+	return &Cache{
+		ttl:      ttl,
+		opts:     append([]Option(nil), opts...),
+		entries:  make(map[string]cacheEntry),
+		inflight: make(map[string]*cacheCall),
+	}
+}
+
+// FetchChain will return the cached certificate chain for the address, fetching it from the remote system when it is
+// not cached or the cached chain has expired.
+func (c *Cache) FetchChain(address string) ([]*CertificateStatus, error) {
+	return c.FetchChainContext(context.Background(), address)
+}
+
+// FetchChainContext is the same as FetchChain but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Cache) FetchChainContext(ctx context.Context, address string) ([]*CertificateStatus, error) {
+	chain, err := c.chain(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return filterRoles(chain, newConfig(c.opts...).roles), nil
+}
+
+// chain will return the complete cached certificate chain for the address, ignoring WithRoles, fetching it when it is
+// not cached or the cached chain has expired. Concurrent misses for the same address wait on a single fetch and
+// share its result, including any error. The shared fetch is not cancelled with any one caller's context, a caller
+// whose context is done stops waiting while the fetch continues, bounded by the configured timeout, for the others.
+func (c *Cache) chain(ctx context.Context, address string) ([]*CertificateStatus, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[address]
+	if ok && now.Before(entry.expires) {
+		c.mu.Unlock()
+		return copyChain(entry.chain), nil
+	}
+	c.evict(now)
+	call, waiting := c.inflight[address]
+	if !waiting {
+		call = &cacheCall{done: make(chan struct{})}
+		c.inflight[address] = call
+		go c.fetch(context.WithoutCancel(ctx), address, call, now)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	return copyChain(call.chain), nil
+}
+
+// fetch will fetch the certificate chain for the address on behalf of every caller waiting on the call, caching it
+// from the time the fetch started when successful.
+func (c *Cache) fetch(ctx context.Context, address string, call *cacheCall, now time.Time) {
+	call.chain, call.err = fetchChain(ctx, address, c.opts...)
+	c.mu.Lock()
+	delete(c.inflight, address)
+	if call.err == nil {
+		c.entries[address] = cacheEntry{chain: call.chain, expires: now.Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// evict will remove every cached chain which has expired, the caller must hold mu.
+func (c *Cache) evict(now time.Time) {
+	for address, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, address)
+		}
+	}
+}
+
+// copyChain will return a copy of the chain, so callers modifying a CertificateStatus do not affect the cache or
+// other callers. The parsed Certificate and byte slices are shared as they are not modified.
+func copyChain(chain []*CertificateStatus) []*CertificateStatus {
+	dup := make([]*CertificateStatus, len(chain))
+	for i, cert := range chain {
+		status := *cert
+		dup[i] = &status
+	}
+	return dup
+}
+
+// Expired is the same as the package level Expired but consults the cache first.
+func (c *Cache) Expired(address string) (bool, error) {
+	return c.ExpiredContext(context.Background(), address)
+}
+
+// ExpiredContext is the same as Expired but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Cache) ExpiredContext(ctx context.Context, address string) (bool, error) {
+	chain, err := c.chain(ctx, address)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpired(chain), nil
+}
+
+// ExpiresWithinDays is the same as the package level ExpiresWithinDays but consults the cache first.
+func (c *Cache) ExpiresWithinDays(address string, days int) (bool, error) {
+	return c.ExpiresWithinDaysContext(context.Background(), address, days)
+}
+
+// ExpiresWithinDaysContext is the same as ExpiresWithinDays but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Cache) ExpiresWithinDaysContext(ctx context.Context, address string, days int) (bool, error) {
+	chain, err := c.chain(ctx, address)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpiresWithinDays(chain, days, newConfig(c.opts...).inclusiveDays), nil
+}
+
+// ExpiresWithin is the same as the package level ExpiresWithin but consults the cache first.
+func (c *Cache) ExpiresWithin(address string, d time.Duration) (bool, error) {
+	return c.ExpiresWithinContext(context.Background(), address, d)
+}
+
+// ExpiresWithinContext is the same as ExpiresWithin but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Cache) ExpiresWithinContext(ctx context.Context, address string, d time.Duration) (bool, error) {
+	chain, err := c.chain(ctx, address)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpiresBefore(chain, newConfig(c.opts...).now().Add(d)), nil
+}
+
+// ExpiresBeforeDate is the same as the package level ExpiresBeforeDate but consults the cache first.
+func (c *Cache) ExpiresBeforeDate(address string, t time.Time) (bool, error) {
+	return c.ExpiresBeforeDateContext(context.Background(), address, t)
+}
+
+// ExpiresBeforeDateContext is the same as ExpiresBeforeDate but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Cache) ExpiresBeforeDateContext(ctx context.Context, address string, t time.Time) (bool, error) {
+	chain, err := c.chain(ctx, address)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpiresBefore(chain, t), nil
+}
+
+// Invalidate will remove the cached certificate chain for the address, the next check will fetch it again.
+func (c *Cache) Invalidate(address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, address)
+}
+
+// Flush will remove every cached certificate chain.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+}
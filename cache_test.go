@@ -0,0 +1,222 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test caching certificate chains per address
+func TestCache(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+	certs, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Logf("Unable to load test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{certs}})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	cache := NewCache(time.Hour)
+	short := NewCache(time.Nanosecond)
+	for _, c := range []*Cache{cache, short} {
+		check, err := c.Expired("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when checking a cached address - %s", err)
+		}
+		if check {
+			t.Errorf("Unexpected expired result from a valid certificate")
+		}
+	}
+
+	// Stop the listener so only cached chains can succeed
+	l.Close()
+	time.Sleep(30 * time.Millisecond)
+
+	t.Run("Cached", func(t *testing.T) {
+		check, err := cache.ExpiresWithinDays("127.0.0.1:9000", 30)
+		if err != nil {
+			t.Fatalf("Unexpected failure when checking a cached address - %s", err)
+		}
+		if check {
+			t.Errorf("Unexpected expires within 30 days result from a certificate expiring in 37 days")
+		}
+		check, err = cache.ExpiresWithin("127.0.0.1:9000", 1000*time.Hour)
+		if err != nil {
+			t.Fatalf("Unexpected failure when checking a cached address - %s", err)
+		}
+		if !check {
+			t.Errorf("Expected certificate expiring in 900 hours to expire within 1000 hours")
+		}
+	})
+
+	t.Run("TTLExpired", func(t *testing.T) {
+		_, err := short.Expired("127.0.0.1:9000")
+		if err == nil {
+			t.Errorf("Expected failure when the cached chain has expired and the address is unreachable, err is nil")
+		}
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		cache.Invalidate("127.0.0.1:9000")
+		_, err := cache.Expired("127.0.0.1:9000")
+		if err == nil {
+			t.Errorf("Expected failure when the cached chain was invalidated and the address is unreachable, err is nil")
+		}
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		cache.mu.Lock()
+		cache.entries["127.0.0.1:9000"] = cacheEntry{expires: time.Now().Add(time.Hour)}
+		cache.mu.Unlock()
+		cache.Flush()
+		_, err := cache.FetchChain("127.0.0.1:9000")
+		if err == nil {
+			t.Errorf("Expected failure when the cache was flushed and the address is unreachable, err is nil")
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := cache.Expired("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test concurrent misses share one fetch and each caller receives its own copy of the chain
+func TestCacheConcurrentMisses(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	var dials int32
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		time.Sleep(50 * time.Millisecond)
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+	cache := NewCache(time.Hour, dialer)
+
+	var wg sync.WaitGroup
+	results := make([][]*CertificateStatus, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.FetchChain("example.com")
+		}(i)
+	}
+	wg.Wait()
+
+	if v := atomic.LoadInt32(&dials); v != 1 {
+		t.Errorf("Unexpected number of connections, expected concurrent misses to share 1 got %d", v)
+	}
+	for i, certs := range results {
+		if len(certs) != 2 {
+			t.Fatalf("Unexpected Certificate Chain for caller %d got %+v", i, certs)
+		}
+	}
+
+	t.Run("Copies", func(t *testing.T) {
+		results[0][0].Source = "modified"
+		results[0][0], results[0][1] = results[0][1], results[0][0]
+		certs, err := cache.FetchChain("example.com")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching a cached address - %s", err)
+		}
+		if certs[0].Source != "example.com" || !certs[0].Certificate.Equal(chain.leaf) || results[1][0].Source != "example.com" {
+			t.Errorf("Unexpected cached chain, a caller's changes leaked into the cache")
+		}
+	})
+}
+
+// Test a caller cancelling does not fail other callers sharing the fetch
+func TestCacheCancelledCaller(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialing := make(chan struct{})
+	release := make(chan struct{})
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		close(dialing)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+	cache := NewCache(time.Hour, dialer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.FetchChainContext(ctx, "example.com")
+		first <- err
+	}()
+	<-dialing
+
+	second := make(chan error, 1)
+	go func() {
+		certs, err := cache.FetchChain("example.com")
+		if err == nil && len(certs) != 2 {
+			err = fmt.Errorf("unexpected Certificate Chain %+v", certs)
+		}
+		second <- err
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled for the cancelled caller, got %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("Unexpected failure for a caller sharing a cancelled caller's fetch - %s", err)
+	}
+}
+
+// Test expired chains are evicted from the cache
+func TestCacheEviction(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf}}}, nil
+	})
+	cache := NewCache(10*time.Millisecond, dialer)
+	for _, address := range []string{"a.example.com", "b.example.com"} {
+		if _, err := cache.FetchChain(address); err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := cache.FetchChain("c.example.com"); err != nil {
+		t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if len(cache.entries) != 1 {
+		t.Errorf("Unexpected number of cached chains, expected expired chains to be evicted got %d", len(cache.entries))
+	}
+}
@@ -0,0 +1,57 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+)
+
+// ChainOrderStatus reports whether a certificate chain is served in the order clients expect, the leaf first with
+// each following certificate signing the one before it.
+type ChainOrderStatus struct {
+	// Valid indicates the chain is correctly ordered, this is true when Problems is empty
+	Valid bool
+
+	// Problems describe each ordering issue found within the chain, such as a misplaced leaf or a missing intermediate
+	Problems []string
+}
+
+// CheckChainOrder will fetch the remote system's certificate chain and report whether it is correctly ordered.
+// Misordered chains and missing intermediates cause client failures even when no certificate is expired.
+func CheckChainOrder(address string, opts ...Option) (ChainOrderStatus, error) {
+	return CheckChainOrderContext(context.Background(), address, opts...)
+}
+
+// CheckChainOrderContext is the same as CheckChainOrder but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func CheckChainOrderContext(ctx context.Context, address string, opts ...Option) (ChainOrderStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return ChainOrderStatus{}, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return ChainOrder(chain), nil
+}
+
+// ChainOrder will report whether an already fetched certificate chain is correctly ordered. The first certificate
+// must be the leaf rather than a CA, and each certificate must be signed by the certificate that follows it. A
+// missing intermediate is reported as a certificate not signed by its successor.
+func ChainOrder(chain []*CertificateStatus) ChainOrderStatus {
+	var problems []string
+	if len(chain) == 0 {
+		problems = append(problems, "No certificates in chain")
+	}
+	for i, cert := range chain {
+		if cert.Certificate == nil {
+			problems = append(problems, fmt.Sprintf("Certificate %d (%s) has no parsed certificate to check", i, cert.Subject))
+			continue
+		}
+		if i == 0 && cert.Certificate.IsCA && len(chain) > 1 {
+			problems = append(problems, fmt.Sprintf("Certificate 0 (%s) is a CA certificate, expected the leaf first", cert.Subject))
+		}
+		if i+1 < len(chain) && chain[i+1].Certificate != nil {
+			err := cert.Certificate.CheckSignatureFrom(chain[i+1].Certificate)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("Certificate %d (%s) is not signed by certificate %d (%s) - %s", i, cert.Subject, i+1, chain[i+1].Subject, err))
+			}
+		}
+	}
+	return ChainOrderStatus{Valid: len(problems) == 0, Problems: problems}
+}
@@ -0,0 +1,69 @@
+package hazexpired
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+// Test detecting misordered certificate chains
+func TestChainOrder(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	other, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	now := time.Now()
+	leaf := newCertificateStatus(chain.leaf, now)
+	ca := newCertificateStatus(chain.ca, now)
+	otherCA := newCertificateStatus(other.ca, now)
+
+	tt := []struct {
+		name     string
+		chain    []*CertificateStatus
+		problems int
+	}{
+		{"Ordered", []*CertificateStatus{leaf, ca}, 0},
+		{"LeafOnly", []*CertificateStatus{leaf}, 0},
+		{"Reversed", []*CertificateStatus{ca, leaf}, 2},
+		{"WrongIssuer", []*CertificateStatus{leaf, otherCA}, 1},
+		{"Empty", nil, 1},
+		{"NotParsed", []*CertificateStatus{{Subject: "CN=example.com"}}, 1},
+	}
+
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			s := ChainOrder(c.chain)
+			if len(s.Problems) != c.problems || s.Valid != (c.problems == 0) {
+				t.Errorf("Unexpected chain order status, expected %d problems got %+v", c.problems, s)
+			}
+		})
+	}
+
+	t.Run("CheckChainOrder", func(t *testing.T) {
+		l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{chain.cert}})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		defer l.Close()
+
+		s, err := CheckChainOrder("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckChainOrder - %s", err)
+		}
+		if !s.Valid {
+			t.Errorf("Unexpected problems with an ordered chain - %+v", s.Problems)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := CheckChainOrder("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
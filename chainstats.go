@@ -0,0 +1,52 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+)
+
+// ChainStats counts the certificates within a certificate chain, surfacing bloated or misconfigured chains such as
+// those serving the same certificate more than once.
+type ChainStats struct {
+	// TotalCerts is the number of certificates within the chain, including duplicates
+	TotalCerts int
+
+	// DistinctIssuers is the number of distinct issuer distinguished names within the chain
+	DistinctIssuers int
+
+	// DuplicateCerts is the number of certificates which repeat an earlier certificate within the chain
+	DuplicateCerts int
+}
+
+// FetchChainStats will fetch the remote system's certificate chain and return a ChainStats counting its certificates.
+func FetchChainStats(address string, opts ...Option) (*ChainStats, error) {
+	return FetchChainStatsContext(context.Background(), address, opts...)
+}
+
+// FetchChainStatsContext is the same as FetchChainStats but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func FetchChainStatsContext(ctx context.Context, address string, opts ...Option) (*ChainStats, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	stats := NewChainStats(chain)
+	return &stats, nil
+}
+
+// NewChainStats will count the certificates within an already fetched certificate chain. Duplicates are identified
+// by their SHA-256 fingerprint.
+func NewChainStats(chain []*CertificateStatus) ChainStats {
+	stats := ChainStats{TotalCerts: len(chain)}
+	issuers := make(map[string]struct{})
+	seen := make(map[string]struct{})
+	for _, status := range chain {
+		issuers[status.Issuer] = struct{}{}
+		if _, ok := seen[status.Fingerprint]; ok {
+			stats.DuplicateCerts++
+			continue
+		}
+		seen[status.Fingerprint] = struct{}{}
+	}
+	stats.DistinctIssuers = len(issuers)
+	return stats
+}
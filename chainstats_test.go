@@ -0,0 +1,68 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test counting the certificates within a chain
+func TestFetchChainStats(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	other, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := func(certs ...*x509.Certificate) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: certs}}, nil
+		})
+	}
+
+	tt := []struct {
+		name  string
+		certs []*x509.Certificate
+		stats ChainStats
+	}{
+		// The leaf is issued by the CA, and the self-signed CA by itself
+		{"Chain", []*x509.Certificate{chain.leaf, chain.ca}, ChainStats{TotalCerts: 2, DistinctIssuers: 1}},
+		{"Duplicate", []*x509.Certificate{chain.leaf, chain.ca, chain.ca}, ChainStats{TotalCerts: 3, DistinctIssuers: 1, DuplicateCerts: 1}},
+		// The unrelated leaf's issuer has the same distinguished name as the chain's CA
+		{"Unrelated", []*x509.Certificate{chain.leaf, chain.ca, other.leaf, chain.leaf}, ChainStats{TotalCerts: 4, DistinctIssuers: 1, DuplicateCerts: 1}},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			stats, err := FetchChainStats("example.com", dialer(c.certs...))
+			if err != nil {
+				t.Fatalf("Unexpected failure when calling FetchChainStats - %s", err)
+			}
+			if *stats != c.stats {
+				t.Errorf("Unexpected chain stats, expected %+v got %+v", c.stats, *stats)
+			}
+		})
+	}
+
+	t.Run("DistinctIssuers", func(t *testing.T) {
+		certs, err := FetchChain("example.com", dialer(chain.leaf, chain.ca))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		certs[1].Issuer = "CN=Another CA"
+		if stats := NewChainStats(certs); stats.DistinctIssuers != 2 {
+			t.Errorf("Unexpected distinct issuers, expected 2 got %d", stats.DistinctIssuers)
+		}
+	})
+
+	t.Run("EmptyChain", func(t *testing.T) {
+		_, err := FetchChainStats("example.com", dialer())
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ChainStatus summarizes a remote system's certificate chain, covering the most common questions in a single call.
+type ChainStatus struct {
+	// AnyExpired indicates if any certificate within the chain is currently expired
+	AnyExpired bool
+
+	// SoonestExpiry is the earliest expiration date of any certificate within the chain
+	SoonestExpiry time.Time
+
+	// LeafExpiresInDays is the ExpiresInDays of the leaf certificate, negative once the leaf has expired
+	LeafExpiresInDays int
+
+	// Chain is the underlying certificate chain, leaf first
+	Chain []*CertificateStatus
+}
+
+// FetchChainStatus will fetch the remote system's certificate chain and return a ChainStatus summarizing it. This
+// complements the granular functions such as Expired and LeafExpiresWithinDays when several answers are needed from
+// the same connection.
+func FetchChainStatus(address string, opts ...Option) (*ChainStatus, error) {
+	return FetchChainStatusContext(context.Background(), address, opts...)
+}
+
+// FetchChainStatusContext is the same as FetchChainStatus but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func FetchChainStatusContext(ctx context.Context, address string, opts ...Option) (*ChainStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	return newChainStatus(chain), nil
+}
+
+// newChainStatus will summarize a non-empty certificate chain.
+func newChainStatus(chain []*CertificateStatus) *ChainStatus {
+	return &ChainStatus{
+		AnyExpired:        anyExpired(chain),
+		SoonestExpiry:     SoonestExpiry(chain).ExpirationDate,
+		LeafExpiresInDays: chain[0].ExpiresInDays,
+		Chain:             chain,
+	}
+}
@@ -0,0 +1,56 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test summarizing a certificate chain
+func TestFetchChainStatus(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := func(certs ...*x509.Certificate) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: certs}}, nil
+		})
+	}
+
+	t.Run("Summary", func(t *testing.T) {
+		s, err := FetchChainStatus("example.com", dialer(chain.leaf, chain.ca))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling FetchChainStatus - %s", err)
+		}
+		if s.AnyExpired {
+			t.Errorf("Unexpected expired result from a valid chain")
+		}
+		if !s.SoonestExpiry.Equal(chain.leaf.NotAfter) {
+			t.Errorf("Unexpected soonest expiry, expected the leaf's %s got %s", chain.leaf.NotAfter, s.SoonestExpiry)
+		}
+		if s.LeafExpiresInDays != 37 {
+			t.Errorf("Unexpected leaf expires in days, expected 37 got %d", s.LeafExpiresInDays)
+		}
+		if len(s.Chain) != 2 {
+			t.Errorf("Unexpected number of certificates, expected 2 got %d", len(s.Chain))
+		}
+	})
+
+	t.Run("EmptyChain", func(t *testing.T) {
+		_, err := FetchChainStatus("example.com", dialer())
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %v", err)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := FetchChainStatus("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package hazexpired
+
+import (
+	"context"
+	"time"
+)
+
+// Client holds a fixed set of options used for every check it performs, allowing configuration such as timeouts,
+// proxies, and trusted roots to be set once and shared. A Client is immutable once created and safe for concurrent
+// use. The package level functions behave like a Client created without options.
+//
+// Options provided to an individual call are applied after the Client's, overriding them for that call only. This
+// lets a single Client probe a mixed fleet, such as one enforcing verification against an internal CA with
+// WithInsecureSkipVerify(false) and WithRootCAs, while passing WithInsecureSkipVerify(true) when checking endpoints
+// whose CA is not trusted. Verification options which take a value can be overridden this way, WithRootCAs(nil)
+// restores the system roots and WithPinnedSPKI(nil) removes any pins. Options without a value, such as
+// WithVerifyHostname and WithFetchIssuers, cannot be turned off once set on the Client.
+//
+//	client := hazexpired.NewClient(hazexpired.WithTimeout(10*time.Second), hazexpired.WithProxy("http://proxy:3128"))
+//	check, err := client.Expired("example.com:443")
+type Client struct {
+	// opts are applied before any options provided to an individual call
+	opts []Option
+}
+
+// NewClient will create a Client which applies the provided options to every check.
+func NewClient(opts ...Option) *Client {
+	return &Client{opts: append([]Option(nil), opts...)}
+}
+
+// with will return the Client's options followed by the provided options, so per call options take precedence.
+func (c *Client) with(opts []Option) []Option {
+	return append(append(make([]Option, 0, len(c.opts)+len(opts)), c.opts...), opts...)
+}
+
+// FetchChain is the same as the package level FetchChain using the Client's options.
+func (c *Client) FetchChain(address string, opts ...Option) ([]*CertificateStatus, error) {
+	return c.FetchChainContext(context.Background(), address, opts...)
+}
+
+// FetchChainContext is the same as FetchChain but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Client) FetchChainContext(ctx context.Context, address string, opts ...Option) ([]*CertificateStatus, error) {
+	return FetchChainContext(ctx, address, c.with(opts)...)
+}
+
+// Expired is the same as the package level Expired using the Client's options.
+func (c *Client) Expired(address string, opts ...Option) (bool, error) {
+	return c.ExpiredContext(context.Background(), address, opts...)
+}
+
+// ExpiredContext is the same as Expired but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Client) ExpiredContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	return ExpiredContext(ctx, address, c.with(opts)...)
+}
+
+// ExpiresWithinDays is the same as the package level ExpiresWithinDays using the Client's options.
+func (c *Client) ExpiresWithinDays(address string, days int, opts ...Option) (bool, error) {
+	return c.ExpiresWithinDaysContext(context.Background(), address, days, opts...)
+}
+
+// ExpiresWithinDaysContext is the same as ExpiresWithinDays but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Client) ExpiresWithinDaysContext(ctx context.Context, address string, days int, opts ...Option) (bool, error) {
+	return ExpiresWithinDaysContext(ctx, address, days, c.with(opts)...)
+}
+
+// ExpiresWithin is the same as the package level ExpiresWithin using the Client's options.
+func (c *Client) ExpiresWithin(address string, d time.Duration, opts ...Option) (bool, error) {
+	return c.ExpiresWithinContext(context.Background(), address, d, opts...)
+}
+
+// ExpiresWithinContext is the same as ExpiresWithin but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Client) ExpiresWithinContext(ctx context.Context, address string, d time.Duration, opts ...Option) (bool, error) {
+	return ExpiresWithinContext(ctx, address, d, c.with(opts)...)
+}
+
+// ExpiresBeforeDate is the same as the package level ExpiresBeforeDate using the Client's options.
+func (c *Client) ExpiresBeforeDate(address string, t time.Time, opts ...Option) (bool, error) {
+	return c.ExpiresBeforeDateContext(context.Background(), address, t, opts...)
+}
+
+// ExpiresBeforeDateContext is the same as ExpiresBeforeDate but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func (c *Client) ExpiresBeforeDateContext(ctx context.Context, address string, t time.Time, opts ...Option) (bool, error) {
+	return ExpiresBeforeDateContext(ctx, address, t, c.with(opts)...)
+}
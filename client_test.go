@@ -0,0 +1,146 @@
+package hazexpired
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test a Client applying its options to every check
+func TestClient(t *testing.T) {
+	// Create cert/key pair
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(chain.ca)
+
+	// Start Listener
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{chain.cert}})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	client := NewClient(WithRootCAs(roots), WithServerName("localhost"))
+
+	t.Run("Options", func(t *testing.T) {
+		certs, err := client.FetchChain("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if certs[0].VerifyError != nil {
+			t.Errorf("Unexpected verify error when the Client trusts the CA - %s", certs[0].VerifyError)
+		}
+	})
+
+	t.Run("CallOptionsOverride", func(t *testing.T) {
+		certs, err := client.FetchChain("127.0.0.1:9000", WithRootCAs(x509.NewCertPool()))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if certs[0].VerifyError == nil {
+			t.Errorf("Expected verify error when the call overrides the Client's roots, err is nil")
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := client.ExpiresWithinDays("127.0.0.1:9000", 30)
+				if err != nil || v {
+					t.Errorf("Unexpected result when calling ExpiresWithinDays concurrently got %+v - %s", v, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Checks", func(t *testing.T) {
+		if v, err := client.Expired("127.0.0.1:9000"); err != nil || v {
+			t.Errorf("Unexpected result when calling Expired got %+v - %s", v, err)
+		}
+		if v, err := client.ExpiresWithin("127.0.0.1:9000", 1000*time.Hour); err != nil || !v {
+			t.Errorf("Unexpected result when calling ExpiresWithin got %+v - %s", v, err)
+		}
+		if v, err := client.ExpiresBeforeDate("127.0.0.1:9000", time.Now().Add(24*time.Hour)); err != nil || v {
+			t.Errorf("Unexpected result when calling ExpiresBeforeDate got %+v - %s", v, err)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := client.Expired("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test overriding a Client's verification options for a single call
+func TestClientVerificationOverride(t *testing.T) {
+	internal, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	untrusted, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	internalRoots := x509.NewCertPool()
+	internalRoots.AddCert(internal.ca)
+	untrustedRoots := x509.NewCertPool()
+	untrustedRoots.AddCert(untrusted.ca)
+
+	// Start Listener presenting a certificate from a CA the Client does not trust
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{untrusted.cert}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	client := NewClient(WithInsecureSkipVerify(false), WithRootCAs(internalRoots), WithServerName("localhost"))
+
+	t.Run("ClientOptions", func(t *testing.T) {
+		_, err := client.FetchChain("127.0.0.1:9000")
+		if err == nil {
+			t.Errorf("Expected failure verifying a certificate from an untrusted CA, err is nil")
+		}
+	})
+
+	t.Run("SkipVerify", func(t *testing.T) {
+		certs, err := client.FetchChain("127.0.0.1:9000", WithInsecureSkipVerify(true))
+		if err != nil {
+			t.Fatalf("Unexpected failure when skipping verification for a single call - %s", err)
+		}
+		if len(certs) == 0 || certs[0].VerifyError == nil {
+			t.Errorf("Expected the untrusted chain to be reported with a VerifyError")
+		}
+	})
+
+	t.Run("RootCAs", func(t *testing.T) {
+		certs, err := client.FetchChain("127.0.0.1:9000", WithRootCAs(untrustedRoots))
+		if err != nil {
+			t.Fatalf("Unexpected failure when trusting a different CA for a single call - %s", err)
+		}
+		if certs[0].VerifyError != nil {
+			t.Errorf("Unexpected VerifyError with the overridden roots - %s", certs[0].VerifyError)
+		}
+	})
+
+	t.Run("NotPersisted", func(t *testing.T) {
+		_, err := client.FetchChain("127.0.0.1:9000")
+		if err == nil {
+			t.Errorf("Expected a per call override not to persist on the Client, err is nil")
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// FetchChainConn will perform a TLS handshake over an already established connection, such as a custom tunnel or an
+// in-memory pipe, and return a CertificateStatus object for each certificate presented. The serverName is sent via
+// SNI and used to verify the leaf certificate. When empty, SNI is skipped and the leaf is not checked against any
+// hostname, as the connection's remote address is not the remote system's hostname. The caller owns the connection and is
+// responsible for closing it, no data beyond the TLS handshake is exchanged. Options which shape how a connection is
+// established, such as WithProxy and WithStartTLS, have no effect.
+func FetchChainConn(conn net.Conn, serverName string, opts ...Option) ([]*CertificateStatus, error) {
+	return FetchChainConnContext(context.Background(), conn, serverName, opts...)
+}
+
+// FetchChainConnContext is the same as FetchChainConn but uses the provided context to cancel or set a deadline on the TLS handshake.
+func FetchChainConnContext(ctx context.Context, conn net.Conn, serverName string, opts ...Option) ([]*CertificateStatus, error) {
+	cfg := newConfig(opts...)
+	cfg.serverName = serverName
+	address := conn.RemoteAddr().String()
+	conf := cfg.tlsConfig(address)
+	if serverName == "" {
+		conf.ServerName = ""
+		cfg.noHostname = true
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+	if cfg.handshakeTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(cfg.handshakeTimeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	c := tls.Client(conn, conf)
+	err := c.HandshakeContext(ctx)
+	if err != nil {
+		return nil, &HandshakeError{Address: address, Err: cfg.handshakeErr(ctx, err)}
+	}
+	state := c.ConnectionState()
+	err = cfg.checkState(state, address)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.newChain(state.PeerCertificates, address), nil
+}
@@ -0,0 +1,92 @@
+package hazexpired
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test fetching a certificate chain over an established connection
+func TestFetchChainConn(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(chain.ca)
+
+	// serve runs a TLS server over one end of an in-memory pipe, returning the other end
+	serve := func() net.Conn {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			_ = tls.Server(server, &tls.Config{Certificates: []tls.Certificate{chain.cert}}).Handshake()
+		}()
+		return client
+	}
+
+	t.Run("Pipe", func(t *testing.T) {
+		conn := serve()
+		defer conn.Close()
+		certs, err := FetchChainConn(conn, "localhost", WithRootCAs(roots))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over a connection - %s", err)
+		}
+		if len(certs) != 2 || certs[0].Certificate.SerialNumber.Int64() != 43 {
+			t.Errorf("Unexpected Certificate Chain fetched over a connection got %+v", certs)
+		}
+		if certs[0].VerifyError != nil {
+			t.Errorf("Unexpected verify error for a trusted chain - %s", certs[0].VerifyError)
+		}
+	})
+
+	t.Run("HostnameMismatch", func(t *testing.T) {
+		conn := serve()
+		defer conn.Close()
+		certs, err := FetchChainConn(conn, "example.com", WithRootCAs(roots))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over a connection - %s", err)
+		}
+		if certs[0].VerifyError == nil {
+			t.Errorf("Expected verify error when the server name does not match the leaf, err is nil")
+		}
+	})
+
+	t.Run("NoServerName", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		sni := make(chan string, 1)
+		go func() {
+			defer server.Close()
+			_ = tls.Server(server, &tls.Config{
+				GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+					sni <- hello.ServerName
+					return nil, nil
+				},
+				Certificates: []tls.Certificate{chain.cert},
+			}).Handshake()
+		}()
+		certs, err := FetchChainConn(client, "", WithRootCAs(roots))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over a connection - %s", err)
+		}
+		if name := <-sni; name != "" {
+			t.Errorf("Unexpected SNI without a server name got %q", name)
+		}
+		if certs[0].VerifyError != nil {
+			t.Errorf("Unexpected verify error without a server name - %s", certs[0].VerifyError)
+		}
+	})
+
+	t.Run("NotTLS", func(t *testing.T) {
+		client, server := net.Pipe()
+		server.Close()
+		defer client.Close()
+		_, err := FetchChainConn(client, "localhost")
+		if err == nil {
+			t.Errorf("Expected failure when the connection is closed, err is nil")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+)
+
+// ConnectionStatus represents the details of the TLS connection made to a remote system along with the certificate
+// chain it presented.
+type ConnectionStatus struct {
+	// Version is the negotiated TLS version, such as "TLS 1.3"
+	Version string
+
+	// CipherSuite is the negotiated cipher suite, such as "TLS_AES_128_GCM_SHA256"
+	CipherSuite string
+
+	// NegotiatedProtocol is the application protocol negotiated via ALPN, such as "h2", this is empty when no
+	// protocol was negotiated
+	NegotiatedProtocol string
+
+	// Resumed indicates the connection resumed an earlier TLS session using WithSessionResumption, the chain is the
+	// one presented during the original full handshake
+	Resumed bool
+
+	// ECHAccepted indicates the remote system accepted Encrypted Client Hello configured with WithECH, the chain is
+	// the one presented for the encrypted inner ClientHello
+	ECHAccepted bool
+
+	// OCSPResponse is the raw DER encoded OCSP response stapled by the remote system during the handshake, this is nil
+	// when no response was stapled
+	OCSPResponse []byte
+
+	// Chain is the remote system's certificate chain
+	Chain []*CertificateStatus
+}
+
+// FetchConnectionStatus will connect to a remote system and return the details of the negotiated TLS connection
+// along with a CertificateStatus object for each certificate in the chain.
+func FetchConnectionStatus(address string, opts ...Option) (*ConnectionStatus, error) {
+	return FetchConnectionStatusContext(context.Background(), address, opts...)
+}
+
+// FetchConnectionStatusContext is the same as FetchConnectionStatus but uses the provided context to cancel or set a deadline on the connection and TLS handshake.
+func FetchConnectionStatusContext(ctx context.Context, address string, opts ...Option) (*ConnectionStatus, error) {
+	cfg := newConfig(opts...)
+	state, err := cfg.fetchState(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	chain := cfg.newChain(state.PeerCertificates, address)
+	if cfg.fetchIssuers {
+		chain = append(chain, cfg.fetchMissingIssuers(ctx, state.PeerCertificates)...)
+	}
+	return &ConnectionStatus{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Resumed:            state.DidResume,
+		ECHAccepted:        state.ECHAccepted,
+		OCSPResponse:       state.OCSPResponse,
+		Chain:              chain,
+	}, nil
+}
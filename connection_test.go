@@ -0,0 +1,309 @@
+package hazexpired
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test fetching connection details and controlling the negotiated TLS version
+func TestFetchConnectionStatus(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+	certs, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Logf("Unable to load test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener supporting TLS 1.2 and 1.3
+	l, err := startConfigListener(&tls.Config{
+		Certificates: []tls.Certificate{certs},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Default", func(t *testing.T) {
+		conn, err := FetchConnectionStatus("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.Version != "TLS 1.3" {
+			t.Errorf("Unexpected TLS version, expected TLS 1.3 got %s", conn.Version)
+		}
+		if !strings.HasPrefix(conn.CipherSuite, "TLS_") {
+			t.Errorf("Unexpected cipher suite name got %s", conn.CipherSuite)
+		}
+		if len(conn.Chain) == 0 {
+			t.Errorf("Unexpected empty Certificate Chain in Connection Status")
+		}
+		if conn.NegotiatedProtocol != "h2" {
+			t.Errorf("Unexpected negotiated protocol, expected the default h2 got %s", conn.NegotiatedProtocol)
+		}
+	})
+
+	t.Run("DisabledALPN", func(t *testing.T) {
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", WithNextProtos(nil))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.NegotiatedProtocol != "" {
+			t.Errorf("Unexpected negotiated protocol without ALPN got %s", conn.NegotiatedProtocol)
+		}
+	})
+
+	t.Run("WithNextProtos", func(t *testing.T) {
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", WithNextProtos([]string{"http/1.1"}))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.NegotiatedProtocol != "http/1.1" {
+			t.Errorf("Unexpected negotiated protocol, expected http/1.1 got %s", conn.NegotiatedProtocol)
+		}
+	})
+
+	t.Run("WithMaxVersion", func(t *testing.T) {
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", WithMaxVersion(tls.VersionTLS12))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.Version != "TLS 1.2" {
+			t.Errorf("Unexpected TLS version, expected TLS 1.2 got %s", conn.Version)
+		}
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		_, err := FetchConnectionStatus("127.0.0.1:9000", WithMinVersion(tls.VersionTLS10), WithMaxVersion(tls.VersionTLS11))
+		var handshakeErr *HandshakeError
+		if !errors.As(err, &handshakeErr) {
+			t.Errorf("Expected HandshakeError when the server does not support the pinned version, got %s", err)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := FetchConnectionStatus("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test completing the handshake with a server which rejects clients not offering h2 via ALPN
+func TestHTTP2OnlyServer(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	// Start Listener rejecting any ClientHello without h2
+	l, err := startConfigListener(&tls.Config{
+		Certificates: []tls.Certificate{chain.cert},
+		NextProtos:   []string{"h2"},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			for _, proto := range hello.SupportedProtos {
+				if proto == "h2" {
+					return nil, nil
+				}
+			}
+			return nil, errors.New("h2 is required")
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Default", func(t *testing.T) {
+		conn, err := FetchConnectionStatus("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status from an HTTP/2 only server - %s", err)
+		}
+		if conn.NegotiatedProtocol != "h2" {
+			t.Errorf("Unexpected negotiated protocol, expected h2 got %s", conn.NegotiatedProtocol)
+		}
+	})
+
+	t.Run("DisabledALPN", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithNextProtos(nil))
+		var handshakeErr *HandshakeError
+		if !errors.As(err, &handshakeErr) {
+			t.Errorf("Expected HandshakeError from an HTTP/2 only server without ALPN, got %v", err)
+		}
+	})
+
+	t.Run("StartTLS", func(t *testing.T) {
+		if protos := newConfig(WithStartTLS("smtp")).alpn(); protos != nil {
+			t.Errorf("Unexpected ALPN protocols offered with StartTLS got %v", protos)
+		}
+		if protos := newConfig(WithStartTLS("smtp"), WithNextProtos([]string{"smtp"})).alpn(); len(protos) != 1 {
+			t.Errorf("Unexpected ALPN protocols offered with StartTLS and WithNextProtos got %v", protos)
+		}
+	})
+}
+
+// Test constraining the cipher suites offered during the handshake
+func TestCipherSuites(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	// Start Listener supporting a single TLS 1.2 cipher suite
+	l, err := startConfigListener(&tls.Config{
+		Certificates: []tls.Certificate{chain.cert},
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Approved", func(t *testing.T) {
+		suites := []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", WithCipherSuites(suites), WithMaxVersion(tls.VersionTLS12))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.CipherSuite != "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256" {
+			t.Errorf("Unexpected cipher suite, expected TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 got %s", conn.CipherSuite)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		suites := []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256}
+		_, err := FetchConnectionStatus("127.0.0.1:9000", WithCipherSuites(suites), WithMaxVersion(tls.VersionTLS12))
+		var handshakeErr *HandshakeError
+		if !errors.As(err, &handshakeErr) {
+			t.Errorf("Expected HandshakeError when the server supports none of the cipher suites, got %v", err)
+		}
+	})
+}
+
+// genECHKey will create an X25519 ECH key for the public name, returning the server key and the serialized
+// ECHConfigList clients use to encrypt the ClientHello
+func genECHKey(publicName string) (tls.EncryptedClientHelloKey, []byte, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return tls.EncryptedClientHelloKey{}, nil, err
+	}
+
+	// ECHConfigContents with DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, and AES-128-GCM
+	var contents []byte
+	contents = append(contents, 1)
+	contents = binary.BigEndian.AppendUint16(contents, 0x0020)
+	contents = binary.BigEndian.AppendUint16(contents, uint16(len(key.PublicKey().Bytes())))
+	contents = append(contents, key.PublicKey().Bytes()...)
+	contents = binary.BigEndian.AppendUint16(contents, 4)
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001)
+	contents = binary.BigEndian.AppendUint16(contents, 0x0001)
+	contents = append(contents, 32, byte(len(publicName)))
+	contents = append(contents, publicName...)
+	contents = binary.BigEndian.AppendUint16(contents, 0)
+
+	config := binary.BigEndian.AppendUint16(nil, 0xfe0d)
+	config = binary.BigEndian.AppendUint16(config, uint16(len(contents)))
+	config = append(config, contents...)
+
+	list := binary.BigEndian.AppendUint16(nil, uint16(len(config)))
+	list = append(list, config...)
+	return tls.EncryptedClientHelloKey{Config: config, PrivateKey: key.Bytes(), SendAsRetry: true}, list, nil
+}
+
+// Test auditing the certificate presented for an Encrypted Client Hello
+func TestECH(t *testing.T) {
+	inner, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	outer, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.Subject.CommonName = "public.example.com"
+		leaf.DNSNames = []string{"public.example.com"}
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	key, configList, err := genECHKey("public.example.com")
+	if err != nil {
+		t.Fatalf("Unable to generate ECH key - %s", err)
+	}
+
+	// Start Listener presenting the real certificate only for the inner ClientHello
+	l, err := startConfigListener(&tls.Config{
+		MinVersion:               tls.VersionTLS13,
+		EncryptedClientHelloKeys: []tls.EncryptedClientHelloKey{key},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == "localhost" {
+				return &inner.cert, nil
+			}
+			return &outer.cert, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Accepted", func(t *testing.T) {
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", WithServerName("localhost"), WithECH(configList))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if !conn.ECHAccepted {
+			t.Errorf("Expected ECH to be accepted")
+		}
+		if !conn.Chain[0].Certificate.Equal(inner.leaf) {
+			t.Errorf("Unexpected certificate, expected the inner certificate got %s", conn.Chain[0].Subject)
+		}
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", WithServerName("public.example.com"))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.ECHAccepted {
+			t.Errorf("Unexpected ECH acceptance without WithECH")
+		}
+		if !conn.Chain[0].Certificate.Equal(outer.leaf) {
+			t.Errorf("Unexpected certificate, expected the public certificate got %s", conn.Chain[0].Subject)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		_, otherList, err := genECHKey("public.example.com")
+		if err != nil {
+			t.Fatalf("Unable to generate ECH key - %s", err)
+		}
+		// the public certificate must be trusted for the rejection to be authenticated
+		roots := x509.NewCertPool()
+		roots.AddCert(outer.ca)
+		_, err = FetchConnectionStatus("127.0.0.1:9000", WithServerName("localhost"), WithECH(otherList), WithRootCAs(roots))
+		var rejection *tls.ECHRejectionError
+		if !errors.As(err, &rejection) {
+			t.Fatalf("Expected an ECHRejectionError when ECH is rejected, got %v", err)
+		}
+		if len(rejection.RetryConfigList) == 0 {
+			t.Errorf("Expected the rejection to include the server's config list")
+		}
+	})
+}
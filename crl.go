@@ -0,0 +1,166 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCRLCacheTTL is how long downloaded CRLs are cached when WithCRLCacheTTL is not provided.
+	defaultCRLCacheTTL = time.Hour
+
+	// maxCRLSize limits the size of CRLs downloaded from a distribution point.
+	maxCRLSize = 64 << 20
+)
+
+// crlCache holds downloaded CRLs keyed by distribution point URL, shared across calls to avoid refetching large lists.
+// Expired entries are removed on each lookup.
+var crlCache = struct {
+	sync.Mutex
+	entries map[string]crlCacheEntry
+}{entries: make(map[string]crlCacheEntry)}
+
+// crlCacheEntry is a cached CRL and the time it should no longer be used.
+type crlCacheEntry struct {
+	list    *x509.RevocationList
+	expires time.Time
+}
+
+// CRLStatus represents whether a certificate is listed as revoked on its issuer's certificate revocation list.
+type CRLStatus struct {
+	// Revoked indicates the certificate's serial number is listed on the CRL
+	Revoked bool
+
+	// RevokedAt is when the certificate was revoked, this is zero unless Revoked is true
+	RevokedAt time.Time
+
+	// DistributionPoint is the URL the CRL was downloaded from
+	DistributionPoint string
+
+	// ThisUpdate is when the CRL was issued
+	ThisUpdate time.Time
+
+	// NextUpdate is when the next CRL will be issued, this is zero if the CRL does not provide it
+	NextUpdate time.Time
+}
+
+// CheckCRL will download the certificate revocation list from the remote system's leaf certificate's CRL
+// distribution point and report whether the leaf is listed as revoked. The CRL signature is verified against the
+// issuer certificate from the fetched chain. Downloaded CRLs are cached until the sooner of their NextUpdate or the
+// cache TTL, which defaults to one hour and can be changed with WithCRLCacheTTL.
+func CheckCRL(address string, opts ...Option) (CRLStatus, error) {
+	return CheckCRLContext(context.Background(), address, opts...)
+}
+
+// CheckCRLContext is the same as CheckCRL but uses the provided context to cancel or set a deadline on fetching the certificate chain and CRL.
+func CheckCRLContext(ctx context.Context, address string, opts ...Option) (CRLStatus, error) {
+	cfg := newConfig(opts...)
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return CRLStatus{}, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	if len(chain) == 0 {
+		return CRLStatus{}, fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	if len(chain) < 2 {
+		return CRLStatus{}, fmt.Errorf("Issuer certificate not presented by outbound address %s, it is required to verify the CRL", address)
+	}
+	return cfg.checkCRL(ctx, chain[0].Certificate, chain[1].Certificate)
+}
+
+// checkCRL will fetch the CRL from the certificate's first distribution point and look up the certificate's serial number.
+func (cfg *config) checkCRL(ctx context.Context, cert, issuer *x509.Certificate) (CRLStatus, error) {
+	if len(cert.CRLDistributionPoints) == 0 {
+		return CRLStatus{}, fmt.Errorf("Certificate %s does not list a CRL distribution point", cert.Subject)
+	}
+	point := cert.CRLDistributionPoints[0]
+
+	list, err := cfg.fetchCRL(ctx, point, issuer)
+	if err != nil {
+		return CRLStatus{}, err
+	}
+
+	status := CRLStatus{
+		DistributionPoint: point,
+		ThisUpdate:        list.ThisUpdate,
+		NextUpdate:        list.NextUpdate,
+	}
+	for _, entry := range list.RevokedCertificateEntries {
+		if entry.SerialNumber != nil && entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			status.Revoked = true
+			status.RevokedAt = entry.RevocationTime
+			break
+		}
+	}
+	return status, nil
+}
+
+// fetchCRL will return the CRL from the distribution point, using the cache when a valid entry exists. The CRL
+// signature is verified against the issuer whether or not it was cached, as the cache is shared by every issuer.
+func (cfg *config) fetchCRL(ctx context.Context, point string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	now := time.Now()
+	if cfg.crlCacheTTL > 0 {
+		crlCache.Lock()
+		for key, entry := range crlCache.entries {
+			if !now.Before(entry.expires) {
+				delete(crlCache.entries, key)
+			}
+		}
+		entry, ok := crlCache.entries[point]
+		crlCache.Unlock()
+		if ok {
+			err := entry.list.CheckSignatureFrom(issuer)
+			if err != nil {
+				return nil, fmt.Errorf("CRL from %s is not signed by the certificate issuer - %w", point, err)
+			}
+			return entry.list, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, point, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create CRL request for %s - %s", point, err)
+	}
+	resp, err := cfg.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Could not download CRL from %s - %w", point, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CRL distribution point %s returned %s", point, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read CRL from %s - %s", point, err)
+	}
+
+	// CRLs are normally DER encoded but some distribution points serve PEM
+	if block, _ := pem.Decode(data); block != nil && block.Type == "X509 CRL" {
+		data = block.Bytes
+	}
+	list, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse CRL from %s - %s", point, err)
+	}
+	err = list.CheckSignatureFrom(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("CRL from %s is not signed by the certificate issuer - %w", point, err)
+	}
+
+	if cfg.crlCacheTTL > 0 {
+		expires := now.Add(cfg.crlCacheTTL)
+		if !list.NextUpdate.IsZero() && list.NextUpdate.Before(expires) {
+			expires = list.NextUpdate
+		}
+		crlCache.Lock()
+		crlCache.entries[point] = crlCacheEntry{list: list, expires: expires}
+		crlCache.Unlock()
+	}
+	return list, nil
+}
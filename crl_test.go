@@ -0,0 +1,169 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test checking the leaf certificate against its issuer's CRL
+func TestCheckCRL(t *testing.T) {
+	var downloads int32
+	var crl []byte
+	distributionPoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		_, _ = w.Write(crl)
+	}))
+	defer distributionPoint.Close()
+
+	// Create a chain whose leaf lists the test distribution point
+	chain, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.CRLDistributionPoints = []string{distributionPoint.URL + "/good.crl"}
+	})
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{chain.cert}})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("NotRevoked", func(t *testing.T) {
+		crl, err = x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: time.Now(),
+			NextUpdate: time.Now().Add(24 * time.Hour),
+			RevokedCertificateEntries: []x509.RevocationListEntry{
+				{SerialNumber: big.NewInt(1000), RevocationTime: time.Now()},
+			},
+		}, chain.ca, chain.caKey)
+		if err != nil {
+			t.Fatalf("Unable to create CRL - %s", err)
+		}
+
+		status, err := CheckCRL("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckCRL - %s", err)
+		}
+		if status.Revoked {
+			t.Errorf("Unexpected CRL status, expected not revoked got %+v", status)
+		}
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		before := atomic.LoadInt32(&downloads)
+		_, err := CheckCRL("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckCRL - %s", err)
+		}
+		if v := atomic.LoadInt32(&downloads); v != before {
+			t.Errorf("Unexpected CRL download, expected cached CRL to be used")
+		}
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		crl, err = x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(2),
+			ThisUpdate: time.Now(),
+			NextUpdate: time.Now().Add(24 * time.Hour),
+			RevokedCertificateEntries: []x509.RevocationListEntry{
+				{SerialNumber: chain.leaf.SerialNumber, RevocationTime: time.Now().Add(-time.Hour)},
+			},
+		}, chain.ca, chain.caKey)
+		if err != nil {
+			t.Fatalf("Unable to create CRL - %s", err)
+		}
+
+		status, err := CheckCRL("127.0.0.1:9000", WithCRLCacheTTL(0))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckCRL - %s", err)
+		}
+		if !status.Revoked || status.RevokedAt.IsZero() {
+			t.Errorf("Unexpected CRL status, expected revoked got %+v", status)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := CheckCRL("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test a cached CRL is only used for chains whose issuer signed it, and stale entries are evicted
+func TestCRLCache(t *testing.T) {
+	var crl []byte
+	distributionPoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(crl)
+	}))
+	defer distributionPoint.Close()
+	point := distributionPoint.URL + "/shared.crl"
+
+	signer, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.CRLDistributionPoints = []string{point}
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	other, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.CRLDistributionPoints = []string{point}
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	crl, err = x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(24 * time.Hour),
+	}, signer.ca, signer.caKey)
+	if err != nil {
+		t.Fatalf("Unable to create CRL - %s", err)
+	}
+	dialer := func(chain *testChain) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+		})
+	}
+
+	_, err = CheckCRL("example.com", dialer(signer))
+	if err != nil {
+		t.Fatalf("Unexpected failure when calling CheckCRL - %s", err)
+	}
+
+	t.Run("OtherIssuer", func(t *testing.T) {
+		_, err := CheckCRL("example.com", dialer(other))
+		if err == nil || !strings.Contains(err.Error(), "not signed by the certificate issuer") {
+			t.Errorf("Expected a cached CRL from another issuer to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		crlCache.Lock()
+		crlCache.entries["http://stale.example.com/stale.crl"] = crlCacheEntry{expires: time.Now().Add(-time.Minute)}
+		crlCache.Unlock()
+		_, err := CheckCRL("example.com", dialer(signer))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckCRL - %s", err)
+		}
+		crlCache.Lock()
+		defer crlCache.Unlock()
+		if _, ok := crlCache.entries["http://stale.example.com/stale.crl"]; ok {
+			t.Errorf("Expected the stale CRL cache entry to be evicted")
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package hazexpired
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader are the columns written by WriteCSV, in order.
+var csvHeader = []string{"address", "subject", "issuer", "expiration_date", "days_remaining", "expired"}
+
+// WriteCSV will write the certificates to w as CSV for use in spreadsheets, starting with a header row followed by
+// one row per certificate. The columns are always address, subject, issuer, expiration_date, days_remaining, and
+// expired. The address is the certificate's Source, the expiration date is in RFC3339 format, and days remaining is
+// the ExpiresInDays, negative once the certificate has expired.
+//
+//	address,subject,issuer,expiration_date,days_remaining,expired
+//	example.com:443,CN=example.com,CN=Example CA,2025-01-02T12:00:00Z,12,false
+func WriteCSV(w io.Writer, results []*CertificateStatus) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+	for _, status := range results {
+		err = cw.Write([]string{
+			status.Source,
+			status.Subject,
+			status.Issuer,
+			status.ExpirationDate.Format(time.RFC3339),
+			strconv.Itoa(status.ExpiresInDays),
+			strconv.FormatBool(status.ExpiredNow),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,66 @@
+package hazexpired
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// errWriter is an io.Writer which always fails
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+// Test writing certificates as CSV
+func TestWriteCSV(t *testing.T) {
+	results := []*CertificateStatus{
+		{
+			Source:         "example.com:443",
+			Subject:        "CN=example.com,O=Example\\, Inc.",
+			Issuer:         "CN=Example CA",
+			ExpirationDate: time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC),
+			ExpiresInDays:  12,
+		},
+		{
+			Source:         "expired.example.com:443",
+			Subject:        "CN=expired.example.com",
+			Issuer:         "CN=Example CA",
+			ExpirationDate: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			ExpiresInDays:  -20,
+			ExpiredNow:     true,
+		},
+	}
+
+	t.Run("Rows", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteCSV(&buf, results)
+		if err != nil {
+			t.Fatalf("Unexpected failure when writing CSV - %s", err)
+		}
+		want := "address,subject,issuer,expiration_date,days_remaining,expired\n" +
+			"example.com:443,\"CN=example.com,O=Example\\, Inc.\",CN=Example CA,2025-01-02T12:00:00Z,12,false\n" +
+			"expired.example.com:443,CN=expired.example.com,CN=Example CA,2024-12-01T00:00:00Z,-20,true\n"
+		if buf.String() != want {
+			t.Errorf("Unexpected CSV output, expected %q got %q", want, buf.String())
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteCSV(&buf, nil)
+		if err != nil {
+			t.Fatalf("Unexpected failure when writing CSV - %s", err)
+		}
+		if buf.String() != "address,subject,issuer,expiration_date,days_remaining,expired\n" {
+			t.Errorf("Expected only the header row for no results, got %q", buf.String())
+		}
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		err := WriteCSV(errWriter{}, results)
+		if err == nil {
+			t.Errorf("Expected failure when the writer fails, err is nil")
+		}
+	})
+}
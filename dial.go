@@ -0,0 +1,234 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Conn is an established TLS connection to a remote system, *tls.Conn satisfies this interface.
+type Conn interface {
+	// ConnectionState returns details of the completed TLS handshake, including the peer certificates
+	ConnectionState() tls.ConnectionState
+
+	// Close closes the connection
+	Close() error
+}
+
+// DialFunc connects to the address over the network and completes a TLS handshake, see WithDialFunc.
+type DialFunc func(ctx context.Context, network, address string) (Conn, error)
+
+// connect will establish a TLS connection to the address using the configured DialFunc, or the built in dial when
+// none is set.
+func (cfg *config) connect(ctx context.Context, address string) (Conn, error) {
+	if cfg.dialFunc == nil {
+		return cfg.dial(ctx, address)
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+	if path, ok := unixSocketPath(address); ok {
+		return cfg.dialFunc(ctx, "unix", path)
+	}
+	normalized, err := normalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.dialFunc(ctx, cfg.network, normalized)
+}
+
+// dial will establish a connection to the address, perform any StartTLS negotiation, and complete the TLS handshake.
+// The configured timeout bounds the whole process. Failures are returned as either a DialError or HandshakeError,
+// except for invalid addresses which are returned as is so they are not retried.
+// Addresses are parsed with NormalizeAddress, addresses of the form unix:/path/to.sock connect to a Unix domain socket.
+// With WithGRPC, certificates received before the remote system closes the connection are returned even if the
+// handshake did not complete.
+func (cfg *config) dial(ctx context.Context, address string) (Conn, error) {
+	path, unix := unixSocketPath(address)
+	if !unix {
+		normalized, err := normalizeAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		address = normalized
+
+		switch cfg.network {
+		case "tcp", "tcp4", "tcp6":
+		case "udp", "udp4", "udp6":
+			return nil, fmt.Errorf("%w, cannot connect to outbound address %s over %s", ErrDTLSUnsupported, address, cfg.network)
+		default:
+			return nil, fmt.Errorf("Unsupported network %s for outbound address %s", cfg.network, address)
+		}
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	var conn net.Conn
+	var err error
+	d := cfg.dialer()
+	switch {
+	case unix:
+		var ud net.Dialer
+		conn, err = ud.DialContext(ctx, "unix", path)
+	case cfg.proxy != "":
+		conn, err = dialHTTPProxy(ctx, d, cfg.proxy, address)
+	case cfg.socks5 != "":
+		conn, err = dialSOCKS5(ctx, d, cfg.socks5, cfg.socks5Auth, address)
+	default:
+		if cfg.localAddr != nil {
+			err = checkLocalAddr(cfg.localAddr, address)
+		}
+		if err == nil {
+			conn, err = d.DialContext(ctx, cfg.network, address)
+		}
+	}
+	if err != nil {
+		return nil, &DialError{Address: address, Err: err}
+	}
+
+	// Interrupt any in-flight reads or writes once the context is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	// Bound the handshake independently of the overall timeout
+	if cfg.handshakeTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(cfg.handshakeTimeout))
+	}
+
+	if cfg.startTLS != "" {
+		err = startTLS(conn, cfg.startTLS)
+		if err != nil {
+			conn.Close()
+			return nil, &HandshakeError{Address: address, Err: cfg.handshakeErr(ctx, err)}
+		}
+	}
+
+	conf := cfg.tlsConfig(address)
+	var captured func() []*x509.Certificate
+	if cfg.grpc {
+		captured = captureCertificates(conf)
+	}
+	c := tls.Client(conn, conf)
+	err = c.HandshakeContext(ctx)
+	if err != nil {
+		conn.Close()
+		if captured != nil && len(captured()) > 0 && ctx.Err() == nil {
+			return &capturedConn{state: tls.ConnectionState{PeerCertificates: captured()}}, nil
+		}
+		return nil, &HandshakeError{Address: address, Err: cfg.handshakeErr(ctx, err)}
+	}
+	if conf.ClientSessionCache != nil && !c.ConnectionState().DidResume {
+		readSessionTickets(c)
+	}
+	if cfg.grpc && c.ConnectionState().NegotiatedProtocol == "h2" {
+		http2Handshake(c)
+	}
+	return c, nil
+}
+
+// dialer will build the dialer used for outbound connections from the configured local address and resolver.
+func (cfg *config) dialer() *net.Dialer {
+	return &net.Dialer{LocalAddr: cfg.localAddr, Resolver: cfg.resolver}
+}
+
+// tlsConfig will build the TLS client configuration used to handshake with the address.
+func (cfg *config) tlsConfig(address string) *tls.Config {
+	conf := &tls.Config{
+		InsecureSkipVerify:             cfg.insecureSkipVerify,
+		RootCAs:                        cfg.rootCAs,
+		ServerName:                     cfg.configuredServerName(address),
+		MinVersion:                     cfg.minVersion,
+		MaxVersion:                     cfg.maxVersion,
+		CipherSuites:                   cfg.cipherSuites,
+		Certificates:                   cfg.clientCertificates,
+		KeyLogWriter:                   cfg.keyLogWriter,
+		NextProtos:                     cfg.alpn(),
+		EncryptedClientHelloConfigList: cfg.echConfigList,
+	}
+	if !cfg.fullHandshake && cfg.sessionCache != nil {
+		conf.ClientSessionCache = addressSessionCache{cache: cfg.sessionCache, address: address}
+	}
+	if conf.ServerName == "" {
+		conf.ServerName = serverName(address)
+	}
+	return conf
+}
+
+// alpn will return the application protocols to offer via ALPN, the configured protocols or h2 and http/1.1 when none
+// are configured, only h2 with WithGRPC. StartTLS protocols are not HTTP, so nothing is offered by default when
+// negotiating StartTLS.
+func (cfg *config) alpn() []string {
+	if cfg.nextProtosSet {
+		return cfg.nextProtos
+	}
+	if cfg.startTLS != "" {
+		return nil
+	}
+	if cfg.grpc {
+		return []string{"h2"}
+	}
+	return []string{"h2", "http/1.1"}
+}
+
+// handshakeErr will wrap the error with ErrHandshakeTimeout when it was caused by the deadline set by
+// WithHandshakeTimeout, rather than the context ending, or with ErrConnectionClosed when the remote system closed
+// the connection.
+func (cfg *config) handshakeErr(ctx context.Context, err error) error {
+	var netErr net.Error
+	if cfg.handshakeTimeout > 0 && ctx.Err() == nil && errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w after %s - %w", ErrHandshakeTimeout, cfg.handshakeTimeout, err)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) {
+		return fmt.Errorf("%w - %w", ErrConnectionClosed, err)
+	}
+	return err
+}
+
+// checkLocalAddr will return an error if the local address is not a TCP address, or if the address being dialed is an
+// IP literal of a different address family than the local address.
+func checkLocalAddr(local net.Addr, address string) error {
+	tcpAddr, ok := local.(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("Local address %s must be a *net.TCPAddr, got %T", local, local)
+	}
+	remote := net.ParseIP(hostFromAddress(address))
+	if remote == nil || tcpAddr.IP == nil {
+		return nil
+	}
+	if (remote.To4() == nil) != (tcpAddr.IP.To4() == nil) {
+		return fmt.Errorf("Local address %s and outbound address %s are different address families", local, address)
+	}
+	return nil
+}
+
+// httpClient will return an HTTP client for fetching supporting data such as OCSP responses, using the configured
+// timeout, local address, and proxy.
+func (cfg *config) httpClient() *http.Client {
+	transport := &http.Transport{
+		DialContext:       cfg.dialer().DialContext,
+		DisableKeepAlives: true,
+	}
+	if cfg.proxy != "" {
+		if u, err := url.Parse(cfg.proxy); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: cfg.timeout}
+}
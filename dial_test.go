@@ -0,0 +1,331 @@
+package hazexpired
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test fetching certificates over a Unix domain socket
+func TestUnixSocket(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	// Start a TLS listener on a Unix socket recording the SNI server name sent by each client
+	path := filepath.Join(t.TempDir(), "tls.sock")
+	names := make(chan string, 10)
+	l, err := tls.Listen("unix", path, &tls.Config{
+		Certificates: []tls.Certificate{chain.cert},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			names <- hello.ServerName
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unable to start Unix socket listener - %s", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	t.Run("ServerName", func(t *testing.T) {
+		certs, err := FetchChain("unix:"+path, WithServerName("localhost"))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over a Unix socket - %s", err)
+		}
+		if len(certs) != 2 || certs[0].Certificate.Subject.CommonName != "localhost" {
+			t.Errorf("Unexpected Certificate Chain over a Unix socket got %+v", certs)
+		}
+		if name := <-names; name != "localhost" {
+			t.Errorf("Unexpected SNI server name over a Unix socket, expected localhost got %q", name)
+		}
+	})
+
+	t.Run("NoServerName", func(t *testing.T) {
+		_, err := FetchChain("unix:" + path)
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over a Unix socket - %s", err)
+		}
+		if name := <-names; name != "" {
+			t.Errorf("Unexpected SNI server name over a Unix socket without WithServerName got %q", name)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := FetchChain("unix:" + filepath.Join(t.TempDir(), "missing.sock"))
+		var dialErr *DialError
+		if !errors.As(err, &dialErr) {
+			t.Errorf("Expected DialError when the Unix socket does not exist, got %v", err)
+		}
+	})
+
+	t.Run("DialFunc", func(t *testing.T) {
+		_, err := FetchChain("unix:"+path, WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			if network != "unix" || address != path {
+				return nil, fmt.Errorf("Unexpected dial to %s %s", network, address)
+			}
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf}}}, nil
+		}))
+		if err != nil {
+			t.Errorf("Unexpected failure when fetching Certificate Chain with a DialFunc - %s", err)
+		}
+	})
+}
+
+// Test binding outbound connections to a local address
+func TestLocalAddr(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("MatchingFamily", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithLocalAddr(&net.TCPAddr{IP: net.ParseIP("127.0.0.1")}))
+		if err != nil {
+			t.Errorf("Unexpected failure when fetching Certificate Chain from a local address - %s", err)
+		}
+	})
+
+	t.Run("MismatchedFamily", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithLocalAddr(&net.TCPAddr{IP: net.ParseIP("::1")}))
+		var dialErr *DialError
+		if !errors.As(err, &dialErr) {
+			t.Errorf("Expected DialError when local and outbound address families differ, got %s", err)
+		}
+	})
+
+	t.Run("InvalidType", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithLocalAddr(&net.UDPAddr{IP: net.ParseIP("127.0.0.1")}))
+		if err == nil {
+			t.Errorf("Expected failure when calling with a non-TCP local address, err is nil")
+		}
+	})
+}
+
+// Test selecting the transport network
+func TestNetwork(t *testing.T) {
+	t.Run("DTLS", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithNetwork("udp"))
+		if !errors.Is(err, ErrDTLSUnsupported) {
+			t.Errorf("Expected ErrDTLSUnsupported when calling with the udp network, got %s", err)
+		}
+	})
+
+	t.Run("IPv6Only", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithNetwork("tcp6"))
+		var dialErr *DialError
+		if !errors.As(err, &dialErr) {
+			t.Errorf("Expected DialError when connecting to an IPv4 address over tcp6, got %v", err)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithNetwork("ip"))
+		if err == nil {
+			t.Errorf("Expected failure when calling with an unsupported network, err is nil")
+		}
+	})
+}
+
+// Test presenting a client certificate to a remote system requiring mutual TLS
+func TestClientCertificate(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener which requires and records client certificates
+	presented := make(chan int, 1)
+	l, err := startConfigListener(&tls.Config{
+		Certificates: []tls.Certificate{chain.cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			presented <- len(rawCerts)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	_, err = FetchChain("127.0.0.1:9000", WithClientCertificate(chain.cert))
+	if err != nil {
+		t.Fatalf("Unexpected failure when fetching Certificate Chain with a client certificate - %s", err)
+	}
+	select {
+	case n := <-presented:
+		if n == 0 {
+			t.Errorf("Expected client certificate to be presented, none received")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for the server to receive a client certificate")
+	}
+}
+
+// Test writing TLS session secrets for debugging
+func TestKeyLogWriter(t *testing.T) {
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	var buf bytes.Buffer
+	_, err = FetchChain("127.0.0.1:9000", WithKeyLogWriter(&buf))
+	if err != nil {
+		t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+	}
+	if !strings.Contains(buf.String(), "CLIENT_HANDSHAKE_TRAFFIC_SECRET") {
+		t.Errorf("Expected key log to contain handshake secrets got %q", buf.String())
+	}
+}
+
+// fakeConn is a Conn returning a canned ConnectionState
+type fakeConn struct {
+	state tls.ConnectionState
+}
+
+func (c *fakeConn) ConnectionState() tls.ConnectionState { return c.state }
+
+func (c *fakeConn) Close() error { return nil }
+
+// Test replacing the built in connection with a DialFunc
+func TestDialFunc(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := func(state tls.ConnectionState) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			if network != "tcp" || address != "example.com:443" {
+				return nil, fmt.Errorf("Unexpected dial to %s %s", network, address)
+			}
+			return &fakeConn{state: state}, nil
+		})
+	}
+
+	t.Run("CannedChain", func(t *testing.T) {
+		certs, err := FetchChain("example.com", dialer(tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if len(certs) != 2 || certs[0].Certificate != chain.leaf {
+			t.Errorf("Unexpected Certificate Chain from DialFunc got %+v", certs)
+		}
+	})
+
+	t.Run("EmptyChain", func(t *testing.T) {
+		certs, err := FetchChain("example.com", dialer(tls.ConnectionState{}))
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %s", err)
+		}
+		if certs != nil {
+			t.Errorf("Expected nil Certificate Chain from an empty chain, got %+v", certs)
+		}
+		expired, err := Expired("example.com", dialer(tls.ConnectionState{}))
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %s", err)
+		}
+		if !expired {
+			t.Errorf("Expected an empty chain to be reported as expired")
+		}
+		_, err = FetchConnectionStatus("example.com", dialer(tls.ConnectionState{}))
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %s", err)
+		}
+		_, err = LeafExpired("example.com", dialer(tls.ConnectionState{}))
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %s", err)
+		}
+		_, _, err = SplitChain("example.com", dialer(tls.ConnectionState{}))
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %s", err)
+		}
+	})
+
+	t.Run("SplitChain", func(t *testing.T) {
+		leaf, intermediates, err := SplitChain("example.com", dialer(tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling SplitChain - %s", err)
+		}
+		if leaf.Certificate != chain.leaf || len(intermediates) != 1 || intermediates[0].Certificate != chain.ca {
+			t.Errorf("Unexpected split of Certificate Chain got %+v and %+v", leaf, intermediates)
+		}
+
+		leaf, intermediates, err = SplitChain("example.com", dialer(tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf}}))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling SplitChain - %s", err)
+		}
+		if leaf.Certificate != chain.leaf || intermediates == nil || len(intermediates) != 0 {
+			t.Errorf("Unexpected split of single certificate chain got %+v and %+v", leaf, intermediates)
+		}
+	})
+
+	t.Run("MaxChainDepth", func(t *testing.T) {
+		long := tls.ConnectionState{}
+		for i := 0; i < defaultMaxChainDepth+1; i++ {
+			long.PeerCertificates = append(long.PeerCertificates, chain.ca)
+		}
+		_, err := FetchChain("example.com", dialer(long))
+		if !errors.Is(err, ErrChainTooLong) {
+			t.Errorf("Expected ErrChainTooLong from a chain over the default depth, got %v", err)
+		}
+
+		_, err = FetchChain("example.com", dialer(tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}), WithMaxChainDepth(1))
+		if !errors.Is(err, ErrChainTooLong) {
+			t.Errorf("Expected ErrChainTooLong from a chain over the configured depth, got %v", err)
+		}
+
+		certs, err := FetchChain("example.com", dialer(long), WithMaxChainDepth(0))
+		if err != nil || len(certs) != len(long.PeerCertificates) {
+			t.Errorf("Unexpected failure when the chain depth is unlimited - %v", err)
+		}
+	})
+
+	t.Run("DialError", func(t *testing.T) {
+		_, err := FetchChain("iamateapot:418", dialer(tls.ConnectionState{}))
+		if err == nil {
+			t.Errorf("Expected failure when the DialFunc fails, err is nil")
+		}
+	})
+}
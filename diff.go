@@ -0,0 +1,141 @@
+package hazexpired
+
+import "time"
+
+// ChainDiff describes how a certificate chain changed between two fetches, as returned by DiffChains.
+type ChainDiff struct {
+	// Added are certificates in the new chain with a subject not present in the old chain
+	Added []*CertificateStatus
+
+	// Removed are certificates in the old chain with a subject not present in the new chain
+	Removed []*CertificateStatus
+
+	// Changed are certificates whose subject is present in both chains but which were replaced, such as a rotated leaf
+	Changed []CertificateChange
+}
+
+// CertificateChange is a certificate which was replaced by another with the same subject.
+type CertificateChange struct {
+	// Old is the certificate from the old chain
+	Old *CertificateStatus
+
+	// New is the certificate from the new chain
+	New *CertificateStatus
+}
+
+// HasChanges will return true if any certificate was added, removed, or changed.
+func (d ChainDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// DiffChains will compare a previously fetched certificate chain, such as one restored from JSON, with a current one and
+// report which certificates were added, removed, or changed. Certificates are identified by their Fingerprint, or by
+// their Issuer and SerialNumber when no fingerprint is available. Certificates which are not identical are matched
+// by Subject, a match is reported as Changed while unmatched certificates are reported as Added or Removed. Results
+// are in chain order.
+func DiffChains(previous, current []*CertificateStatus) ChainDiff {
+	var d ChainDiff
+
+	// Drop certificates found unchanged in both chains
+	unchanged := make(map[string]bool, len(previous))
+	for _, cert := range previous {
+		unchanged[certificateKey(cert)] = true
+	}
+	var added []*CertificateStatus
+	kept := make(map[string]bool, len(current))
+	for _, cert := range current {
+		key := certificateKey(cert)
+		if unchanged[key] {
+			kept[key] = true
+			continue
+		}
+		added = append(added, cert)
+	}
+	var removed []*CertificateStatus
+	for _, cert := range previous {
+		if !kept[certificateKey(cert)] {
+			removed = append(removed, cert)
+		}
+	}
+
+	// Pair the remaining certificates by subject
+	bySubject := make(map[string]*CertificateStatus, len(removed))
+	for _, cert := range removed {
+		if _, ok := bySubject[cert.Subject]; !ok {
+			bySubject[cert.Subject] = cert
+		}
+	}
+	paired := make(map[*CertificateStatus]bool, len(removed))
+	for _, cert := range added {
+		prev, ok := bySubject[cert.Subject]
+		if !ok {
+			d.Added = append(d.Added, cert)
+			continue
+		}
+		delete(bySubject, cert.Subject)
+		paired[prev] = true
+		d.Changed = append(d.Changed, CertificateChange{Old: prev, New: cert})
+	}
+	for _, cert := range removed {
+		if !paired[cert] {
+			d.Removed = append(d.Removed, cert)
+		}
+	}
+	return d
+}
+
+// ValidityGap is a period during which neither of two certificate chains is valid, as returned by RotationGap.
+type ValidityGap struct {
+	// Start is when the previous chain stops being valid, the soonest ExpirationDate within it
+	Start time.Time
+
+	// End is when the current chain becomes valid, the latest NotBefore within it
+	End time.Time
+}
+
+// Duration returns the length of the gap.
+func (g ValidityGap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// RotationGap will compare the validity windows of a previous and current certificate chain, such as before and after
+// a certificate rotation, and report whether there is a gap between them. A chain is valid from the latest NotBefore
+// to the soonest ExpirationDate of its certificates, so an intermediate expiring before the leaf shortens the window.
+// A gap exists when the current chain only becomes valid after the previous chain has expired, leaving clients with
+// no valid certificate in between. Certificates within each chain may be in any order, and false is returned when
+// either chain is empty.
+func RotationGap(previous, current []*CertificateStatus) (ValidityGap, bool) {
+	if len(previous) == 0 || len(current) == 0 {
+		return ValidityGap{}, false
+	}
+	_, expires := validityWindow(previous)
+	starts, _ := validityWindow(current)
+	if !starts.After(expires) {
+		return ValidityGap{}, false
+	}
+	return ValidityGap{Start: expires, End: starts}, true
+}
+
+// validityWindow will return the period during which every certificate within the chain is valid, from the latest
+// NotBefore to the soonest ExpirationDate.
+func validityWindow(chain []*CertificateStatus) (time.Time, time.Time) {
+	var start, end time.Time
+	for i, cert := range chain {
+		if i == 0 || cert.NotBefore.After(start) {
+			start = cert.NotBefore
+		}
+		if i == 0 || cert.ExpirationDate.Before(end) {
+			end = cert.ExpirationDate
+		}
+	}
+	return start, end
+}
+
+// certificateKey will return a string identifying the certificate, its Fingerprint when available, otherwise its
+// Issuer and SerialNumber.
+func certificateKey(cert *CertificateStatus) string {
+	if cert.Fingerprint != "" {
+		return cert.Fingerprint
+	}
+	return cert.Issuer + "/" + cert.SerialNumber.String()
+}
@@ -0,0 +1,108 @@
+package hazexpired
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Test comparing certificate chains to detect rotation
+func TestDiffChains(t *testing.T) {
+	leaf := &CertificateStatus{Subject: "CN=example.com", Fingerprint: "AA"}
+	rotated := &CertificateStatus{Subject: "CN=example.com", Fingerprint: "BB"}
+	intermediate := &CertificateStatus{Subject: "CN=Example Intermediate", Fingerprint: "CC"}
+	newIntermediate := &CertificateStatus{Subject: "CN=Other Intermediate", Fingerprint: "DD"}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		d := DiffChains([]*CertificateStatus{leaf, intermediate}, []*CertificateStatus{leaf, intermediate})
+		if d.HasChanges() {
+			t.Errorf("Unexpected changes between identical chains got %+v", d)
+		}
+	})
+
+	t.Run("Rotated", func(t *testing.T) {
+		d := DiffChains([]*CertificateStatus{leaf, intermediate}, []*CertificateStatus{rotated, intermediate})
+		if len(d.Changed) != 1 || d.Changed[0].Old != leaf || d.Changed[0].New != rotated {
+			t.Errorf("Expected rotated leaf to be reported as changed got %+v", d)
+		}
+		if len(d.Added) != 0 || len(d.Removed) != 0 {
+			t.Errorf("Unexpected added or removed certificates got %+v", d)
+		}
+	})
+
+	t.Run("AddedRemoved", func(t *testing.T) {
+		d := DiffChains([]*CertificateStatus{leaf, intermediate}, []*CertificateStatus{leaf, newIntermediate})
+		if len(d.Added) != 1 || d.Added[0] != newIntermediate {
+			t.Errorf("Expected new intermediate to be reported as added got %+v", d)
+		}
+		if len(d.Removed) != 1 || d.Removed[0] != intermediate {
+			t.Errorf("Expected old intermediate to be reported as removed got %+v", d)
+		}
+		if len(d.Changed) != 0 {
+			t.Errorf("Unexpected changed certificates got %+v", d)
+		}
+	})
+
+	t.Run("SerialNumber", func(t *testing.T) {
+		old := &CertificateStatus{Subject: "CN=example.com", Issuer: "CN=CA", SerialNumber: big.NewInt(42)}
+		same := &CertificateStatus{Subject: "CN=example.com", Issuer: "CN=CA", SerialNumber: big.NewInt(42)}
+		renewed := &CertificateStatus{Subject: "CN=example.com", Issuer: "CN=CA", SerialNumber: big.NewInt(43)}
+		if d := DiffChains([]*CertificateStatus{old}, []*CertificateStatus{same}); d.HasChanges() {
+			t.Errorf("Unexpected changes between certificates with the same serial number got %+v", d)
+		}
+		if d := DiffChains([]*CertificateStatus{old}, []*CertificateStatus{renewed}); len(d.Changed) != 1 {
+			t.Errorf("Expected certificate with a new serial number to be reported as changed got %+v", d)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		d := DiffChains(nil, []*CertificateStatus{leaf})
+		if len(d.Added) != 1 {
+			t.Errorf("Expected certificate to be reported as added to an empty chain got %+v", d)
+		}
+	})
+}
+
+// Test detecting a validity gap between a previous and current chain
+func TestRotationGap(t *testing.T) {
+	base := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	window := func(notBefore, expires time.Time) *CertificateStatus {
+		return &CertificateStatus{NotBefore: notBefore, ExpirationDate: expires}
+	}
+	old := []*CertificateStatus{window(base.AddDate(0, -3, 0), base), window(base.AddDate(-1, 0, 0), base.AddDate(1, 0, 0))}
+
+	tt := []struct {
+		name     string
+		previous []*CertificateStatus
+		current  []*CertificateStatus
+		gap      bool
+		duration time.Duration
+	}{
+		{"Overlap", old, []*CertificateStatus{window(base.AddDate(0, 0, -30), base.AddDate(0, 3, 0))}, false, 0},
+		{"Contiguous", old, []*CertificateStatus{window(base, base.AddDate(0, 3, 0))}, false, 0},
+		{"Gap", old, []*CertificateStatus{window(base.Add(6*time.Hour), base.AddDate(0, 3, 0))}, true, 6 * time.Hour},
+		// the current chain is only valid once its intermediate is
+		{"IntermediateGap", old, []*CertificateStatus{
+			window(base.AddDate(0, 0, -1), base.AddDate(0, 3, 0)),
+			window(base.Add(time.Hour), base.AddDate(2, 0, 0)),
+		}, true, time.Hour},
+		// the previous chain expires with its intermediate
+		{"ExpiringIntermediate", []*CertificateStatus{
+			window(base.AddDate(0, -3, 0), base),
+			window(base.AddDate(-1, 0, 0), base.AddDate(0, 0, -2)),
+		}, []*CertificateStatus{window(base.AddDate(0, 0, -1), base.AddDate(0, 3, 0))}, true, 24 * time.Hour},
+		{"EmptyPrevious", nil, old, false, 0},
+		{"EmptyCurrent", old, nil, false, 0},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			gap, ok := RotationGap(c.previous, c.current)
+			if ok != c.gap {
+				t.Fatalf("Unexpected gap detection, expected %t got %t - %+v", c.gap, ok, gap)
+			}
+			if gap.Duration() != c.duration {
+				t.Errorf("Unexpected gap duration, expected %s got %s", c.duration, gap.Duration())
+			}
+		})
+	}
+}
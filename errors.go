@@ -0,0 +1,70 @@
+package hazexpired
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoCertificates is returned when a remote system completes the TLS handshake without presenting any certificates.
+var ErrNoCertificates = errors.New("No certificates presented")
+
+// ErrChainTooLong is returned when a remote system presents more certificates than allowed by WithMaxChainDepth.
+var ErrChainTooLong = errors.New("Certificate chain too long")
+
+// ErrHandshakeTimeout is returned within a HandshakeError when the handshake does not complete within the time set
+// by WithHandshakeTimeout.
+var ErrHandshakeTimeout = errors.New("TLS handshake timed out")
+
+// ErrConnectionClosed is returned within a HandshakeError when the remote system closes the connection before
+// presenting its certificates.
+var ErrConnectionClosed = errors.New("Connection closed before certificates were presented")
+
+// ErrNotYetValid is returned by ValidAt when the leaf certificate is not yet valid at the requested time.
+var ErrNotYetValid = errors.New("Certificate not yet valid")
+
+// ErrExpired is returned by ValidAt when the leaf certificate has expired at the requested time.
+var ErrExpired = errors.New("Certificate expired")
+
+// ErrDTLSUnsupported is returned when a DTLS handshake over udp is requested with WithNetwork.
+var ErrDTLSUnsupported = errors.New("DTLS is not supported")
+
+// DialError is returned when a connection to the remote system could not be established, such as DNS resolution
+// failures, refused connections, or unreachable hosts. These are often transient network issues rather than
+// certificate problems.
+type DialError struct {
+	// Address is the outbound address being connected to
+	Address string
+
+	// Err is the underlying connection error
+	Err error
+}
+
+// Error returns the error message for a failed connection.
+func (e *DialError) Error() string {
+	return fmt.Sprintf("Could not establish connection to outbound address %s - %s", e.Address, e.Err)
+}
+
+// Unwrap returns the underlying connection error.
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// HandshakeError is returned when a connection was established but the StartTLS negotiation or TLS handshake with the
+// remote system failed.
+type HandshakeError struct {
+	// Address is the outbound address being connected to
+	Address string
+
+	// Err is the underlying handshake error
+	Err error
+}
+
+// Error returns the error message for a failed handshake.
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("TLS handshake with outbound address %s failed - %s", e.Address, e.Err)
+}
+
+// Unwrap returns the underlying handshake error.
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
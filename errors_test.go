@@ -0,0 +1,55 @@
+package hazexpired
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test that connection and handshake failures can be distinguished
+func TestErrorTypes(t *testing.T) {
+	t.Run("DialError", func(t *testing.T) {
+		_, err := Expired("iamateapot:418")
+		var dialErr *DialError
+		if !errors.As(err, &dialErr) {
+			t.Fatalf("Expected DialError when calling with an invalid address, got %T - %s", err, err)
+		}
+		if dialErr.Address != "iamateapot:418" {
+			t.Errorf("Unexpected address on DialError got %s", dialErr.Address)
+		}
+		var handshakeErr *HandshakeError
+		if errors.As(err, &handshakeErr) {
+			t.Errorf("Unexpected HandshakeError when calling with an invalid address")
+		}
+	})
+
+	t.Run("HandshakeError", func(t *testing.T) {
+		// Start a plain TCP listener which closes connections without a TLS handshake
+		l, err := net.Listen("tcp", "0.0.0.0:9000")
+		if err != nil {
+			t.Fatalf("Could not start test listener - %s", err)
+		}
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+		time.Sleep(30 * time.Millisecond)
+
+		_, err = FetchChain("127.0.0.1:9000")
+		var handshakeErr *HandshakeError
+		if !errors.As(err, &handshakeErr) {
+			t.Fatalf("Expected HandshakeError when server closes the connection, got %T - %s", err, err)
+		}
+		var dialErr *DialError
+		if errors.As(err, &dialErr) {
+			t.Errorf("Unexpected DialError when server closes the connection")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExpiryState is the outcome of CheckExpired, distinguishing an unreachable remote system from an expired one.
+type ExpiryState int
+
+const (
+	// ExpiryUnknown indicates the certificate chain could not be fetched, so whether it has expired is unknown
+	ExpiryUnknown ExpiryState = iota
+
+	// ExpiryValid indicates no certificate within the chain has expired
+	ExpiryValid
+
+	// ExpiryExpired indicates a certificate within the chain has expired
+	ExpiryExpired
+)
+
+// String returns the name of the expiry state.
+func (s ExpiryState) String() string {
+	switch s {
+	case ExpiryUnknown:
+		return "unknown"
+	case ExpiryValid:
+		return "valid"
+	case ExpiryExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("ExpiryState(%d)", int(s))
+}
+
+// CheckExpired will check for an expired certificate within the remote system's certificate chain in the same way as
+// Expired, but reports the outcome as one of three states. When the chain cannot be fetched, such as when the remote
+// system is unreachable or the TLS handshake fails, ExpiryUnknown is returned along with the error rather than
+// treating the failure as expired. Callers which only inspect the state cannot mistake an outage for an expiry.
+func CheckExpired(address string, opts ...Option) (ExpiryState, error) {
+	return CheckExpiredContext(context.Background(), address, opts...)
+}
+
+// CheckExpiredContext is the same as CheckExpired but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func CheckExpiredContext(ctx context.Context, address string, opts ...Option) (ExpiryState, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return ExpiryUnknown, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	if anyExpired(chain) {
+		return ExpiryExpired, nil
+	}
+	return ExpiryValid, nil
+}
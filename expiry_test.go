@@ -0,0 +1,60 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+// Test checking for expired certificates with a tri-state result
+func TestCheckExpired(t *testing.T) {
+	valid, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	expired, err := genChain(time.Now().Add(-time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := func(certs ...*x509.Certificate) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: certs}}, nil
+		})
+	}
+
+	tt := []struct {
+		name    string
+		address string
+		opts    []Option
+		state   ExpiryState
+		err     bool
+	}{
+		{"Valid", "example.com", []Option{dialer(valid.leaf, valid.ca)}, ExpiryValid, false},
+		{"Expired", "example.com", []Option{dialer(expired.leaf, expired.ca)}, ExpiryExpired, false},
+		{"Unreachable", "127.0.0.1:1", nil, ExpiryUnknown, true},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			state, err := CheckExpired(c.address, c.opts...)
+			if c.err && err == nil {
+				t.Errorf("Expected failure when calling CheckExpired, err is nil")
+			}
+			if !c.err && err != nil {
+				t.Errorf("Unexpected failure when calling CheckExpired - %s", err)
+			}
+			if state != c.state {
+				t.Errorf("Unexpected state from CheckExpired, expected %s got %s", c.state, state)
+			}
+		})
+	}
+
+	t.Run("String", func(t *testing.T) {
+		for state, name := range map[ExpiryState]string{ExpiryUnknown: "unknown", ExpiryValid: "valid", ExpiryExpired: "expired", 7: "ExpiryState(7)"} {
+			if v := state.String(); v != name {
+				t.Errorf("Unexpected name for state, expected %s got %s", name, v)
+			}
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// CertNotFoundError is returned by FindCert and FindCertFunc when no certificate within the remote system's
+// certificate chain matches.
+type CertNotFoundError struct {
+	// Address is the outbound address being connected to
+	Address string
+
+	// Match is the string being matched, empty when matching with FindCertFunc
+	Match string
+}
+
+// Error returns the error message for an unmatched certificate.
+func (e *CertNotFoundError) Error() string {
+	if e.Match == "" {
+		return fmt.Sprintf("No matching certificate presented by outbound address %s", e.Address)
+	}
+	return fmt.Sprintf("No certificate matching %q presented by outbound address %s", e.Match, e.Address)
+}
+
+// FindCert will fetch the remote system's certificate chain and return the first certificate whose subject common
+// name, subject distinguished name, or serial number matches. Serial numbers may be given in decimal or in hex, with
+// or without colons. A serial of only digits is matched as decimal, so hex serials without a letter must be given
+// with colons or a 0x prefix, such as 10:00 or 0x1000. A *CertNotFoundError is returned when no certificate matches.
+func FindCert(address string, match string, opts ...Option) (*CertificateStatus, error) {
+	return FindCertContext(context.Background(), address, match, opts...)
+}
+
+// FindCertContext is the same as FindCert but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func FindCertContext(ctx context.Context, address string, match string, opts ...Option) (*CertificateStatus, error) {
+	cert, err := FindCertFuncContext(ctx, address, func(status *CertificateStatus) bool {
+		return certMatches(status, match)
+	}, opts...)
+	if e, ok := err.(*CertNotFoundError); ok {
+		e.Match = match
+	}
+	return cert, err
+}
+
+// FindCertFunc will fetch the remote system's certificate chain and return the first certificate for which the
+// match function returns true. A *CertNotFoundError is returned when no certificate matches.
+func FindCertFunc(address string, match func(*CertificateStatus) bool, opts ...Option) (*CertificateStatus, error) {
+	return FindCertFuncContext(context.Background(), address, match, opts...)
+}
+
+// FindCertFuncContext is the same as FindCertFunc but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func FindCertFuncContext(ctx context.Context, address string, match func(*CertificateStatus) bool, opts ...Option) (*CertificateStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	for _, status := range chain {
+		if match(status) {
+			return status, nil
+		}
+	}
+	return nil, &CertNotFoundError{Address: address}
+}
+
+// certMatches will return true if the match is the certificate's subject common name, subject distinguished name, or
+// serial number in decimal or hex. A match of only digits is decimal, so it cannot also match another serial in hex.
+func certMatches(status *CertificateStatus, match string) bool {
+	if match == "" {
+		return false
+	}
+	if status.Subject == match || (status.Certificate != nil && status.Certificate.Subject.CommonName == match) {
+		return true
+	}
+	if status.SerialNumber == nil {
+		return false
+	}
+	if strings.Trim(match, "0123456789") == "" {
+		serial, ok := new(big.Int).SetString(match, 10)
+		return ok && serial.Cmp(status.SerialNumber) == 0
+	}
+	hex := strings.ReplaceAll(match, ":", "")
+	hex = strings.TrimPrefix(strings.TrimPrefix(hex, "0x"), "0X")
+	serial, ok := new(big.Int).SetString(hex, 16)
+	return ok && serial.Cmp(status.SerialNumber) == 0
+}
@@ -0,0 +1,100 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Test finding a certificate within the chain by subject or serial number
+func TestFindCert(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.SerialNumber = big.NewInt(4096)
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+
+	tt := map[string]*x509.Certificate{
+		"I Can Haz Expired Certs CA":             chain.ca,
+		"CN=localhost,O=I Can Haz Expired Certs": chain.leaf,
+		"4096":                                   chain.leaf,
+		"10:00":                                  chain.leaf,
+		"2a":                                     chain.ca,
+		"002A":                                   chain.ca,
+		"0x1000":                                 chain.leaf,
+		"42":                                     chain.ca,
+	}
+	for match, want := range tt {
+		t.Run(match, func(t *testing.T) {
+			status, err := FindCert("example.com", match, dialer)
+			if err != nil {
+				t.Fatalf("Unexpected failure when finding certificate - %s", err)
+			}
+			if status.Certificate != want {
+				t.Errorf("Unexpected certificate found for %q got %s", match, status.Subject)
+			}
+		})
+	}
+
+	t.Run("DecimalNotHex", func(t *testing.T) {
+		// 1000 is not a serial in decimal, and must not match the leaf's serial of 0x1000
+		_, err := FindCert("example.com", "1000", dialer)
+		var notFound *CertNotFoundError
+		if !errors.As(err, &notFound) {
+			t.Errorf("Expected CertNotFoundError for a decimal match of another serial in hex, got %v", err)
+		}
+	})
+
+	t.Run("ZeroSerial", func(t *testing.T) {
+		status := &CertificateStatus{SerialNumber: big.NewInt(0)}
+		for _, match := range []string{"0", "00", "00:00", "0x0"} {
+			if !certMatches(status, match) {
+				t.Errorf("Expected %q to match a zero serial", match)
+			}
+		}
+	})
+
+	t.Run("Func", func(t *testing.T) {
+		status, err := FindCertFunc("example.com", func(s *CertificateStatus) bool {
+			return s.Certificate.IsCA
+		}, dialer)
+		if err != nil {
+			t.Fatalf("Unexpected failure when finding certificate - %s", err)
+		}
+		if status.Certificate != chain.ca {
+			t.Errorf("Unexpected certificate found got %s", status.Subject)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := FindCert("example.com", "example.org", dialer)
+		var notFound *CertNotFoundError
+		if !errors.As(err, &notFound) {
+			t.Fatalf("Expected CertNotFoundError when no certificate matches, got %v", err)
+		}
+		if notFound.Match != "example.org" || notFound.Address != "example.com" {
+			t.Errorf("Unexpected CertNotFoundError details got %+v", notFound)
+		}
+		_, err = FindCertFunc("example.com", func(*CertificateStatus) bool { return false }, dialer)
+		if !errors.As(err, &notFound) {
+			t.Errorf("Expected CertNotFoundError when no certificate matches, got %v", err)
+		}
+	})
+
+	t.Run("FetchError", func(t *testing.T) {
+		_, err := FindCert("example.com", "localhost", WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{}, nil
+		}))
+		if !errors.Is(err, ErrNoCertificates) {
+			t.Errorf("Expected ErrNoCertificates from an empty chain, got %v", err)
+		}
+	})
+}
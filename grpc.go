@@ -0,0 +1,75 @@
+package hazexpired
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+)
+
+// http2Preface is the client connection preface which begins every HTTP/2 connection, followed by a SETTINGS frame.
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// http2Linger bounds how long a connection is kept open after the handshake waiting for the server's SETTINGS frame.
+const http2Linger = time.Second
+
+// capturedConn is a Conn holding certificates captured during a handshake that the remote system closed before it
+// completed.
+type capturedConn struct {
+	state tls.ConnectionState
+}
+
+func (c *capturedConn) ConnectionState() tls.ConnectionState { return c.state }
+
+func (c *capturedConn) Close() error { return nil }
+
+// captureCertificates will set the TLS configuration to record the remote system's certificates as soon as they are
+// received, returning a function which reports the certificates captured so far.
+func captureCertificates(conf *tls.Config) func() []*x509.Certificate {
+	var captured []*x509.Certificate
+	conf.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return nil
+			}
+			captured = append(captured, cert)
+		}
+		return nil
+	}
+	return func() []*x509.Certificate {
+		return captured
+	}
+}
+
+// http2Handshake will send the HTTP/2 client preface and an empty SETTINGS frame, then read frames until the server
+// sends its own SETTINGS frame, the server closes the connection, or the linger time passes. This keeps the
+// connection open long enough to look like a well behaved client to gRPC servers and load balancers.
+func http2Handshake(conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(http2Linger))
+	defer conn.SetDeadline(time.Time{})
+
+	// An empty SETTINGS frame is a zero length, type 0x4 frame on stream 0
+	_, err := conn.Write(append([]byte(http2Preface), 0, 0, 0, 0x4, 0, 0, 0, 0, 0))
+	if err != nil {
+		return
+	}
+	header := make([]byte, 9)
+	for {
+		_, err = io.ReadFull(conn, header)
+		if err != nil {
+			return
+		}
+		length := int(binary.BigEndian.Uint32(append([]byte{0}, header[:3]...)))
+		_, err = io.CopyN(io.Discard, conn, int64(length))
+		if err != nil {
+			return
+		}
+		// SETTINGS without the ACK flag
+		if header[3] == 0x4 && header[4]&0x1 == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package hazexpired
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test probing gRPC and other HTTP/2 only servers
+func TestGRPC(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	t.Run("Preface", func(t *testing.T) {
+		l, err := tls.Listen("tcp", "0.0.0.0:9000", &tls.Config{
+			Certificates: []tls.Certificate{chain.cert},
+			NextProtos:   []string{"h2"},
+		})
+		if err != nil {
+			t.Fatalf("Could not start test listener - %s", err)
+		}
+		defer l.Close()
+
+		// Reply to the client preface with the server's SETTINGS frame
+		prefaces := make(chan []byte, 1)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			b := make([]byte, len(http2Preface)+9)
+			_, err = io.ReadFull(conn, b)
+			if err != nil {
+				return
+			}
+			prefaces <- b
+			_, _ = conn.Write([]byte{0, 0, 0, 0x4, 0, 0, 0, 0, 0})
+		}()
+
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", WithGRPC())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status in gRPC mode - %s", err)
+		}
+		if conn.NegotiatedProtocol != "h2" || len(conn.Chain) != 2 {
+			t.Errorf("Unexpected Connection Status in gRPC mode got %+v", conn)
+		}
+		select {
+		case b := <-prefaces:
+			if !bytes.HasPrefix(b, []byte(http2Preface)) || b[len(http2Preface)+3] != 0x4 {
+				t.Errorf("Unexpected HTTP/2 preface sent got %q", b)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Timed out waiting for the HTTP/2 preface")
+		}
+	})
+
+	t.Run("ClosedAfterCertificates", func(t *testing.T) {
+		// A TLS 1.2 server requiring a client certificate aborts the handshake after presenting its own
+		l, err := startConfigListener(&tls.Config{
+			Certificates: []tls.Certificate{chain.cert},
+			MaxVersion:   tls.VersionTLS12,
+			ClientAuth:   tls.RequireAnyClientCert,
+		})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+		time.Sleep(30 * time.Millisecond)
+
+		_, err = FetchChain("127.0.0.1:9000")
+		var handshakeErr *HandshakeError
+		if !errors.As(err, &handshakeErr) {
+			t.Errorf("Expected HandshakeError without gRPC mode, got %v", err)
+		}
+
+		certs, err := FetchChain("127.0.0.1:9000", WithGRPC())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain in gRPC mode - %s", err)
+		}
+		if len(certs) != 2 || certs[0].Certificate.Subject.CommonName != "localhost" {
+			t.Errorf("Unexpected Certificate Chain captured in gRPC mode got %+v", certs)
+		}
+	})
+
+	t.Run("ClosedBeforeCertificates", func(t *testing.T) {
+		l, err := net.Listen("tcp", "0.0.0.0:9000")
+		if err != nil {
+			t.Fatalf("Could not start test listener - %s", err)
+		}
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+
+		_, err = FetchChain("127.0.0.1:9000", WithGRPC())
+		if !errors.Is(err, ErrConnectionClosed) {
+			t.Errorf("Expected ErrConnectionClosed when the server closes before presenting certificates, got %v", err)
+		}
+	})
+}
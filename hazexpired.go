@@ -12,10 +12,15 @@
 package hazexpired
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"math"
 	"math/big"
-	"net"
+	"strings"
 	"time"
 )
 
@@ -24,90 +29,625 @@ type CertificateStatus struct {
 	// ExpiredNow indicates if this certificate is expired currently
 	ExpiredNow bool
 
-	// ExpiresInDays is an numeric count of days this certificate will expire
+	// ExpiresInDays is an numeric count of days this certificate will expire, negative once the certificate has expired
 	ExpiresInDays int
 
 	// ExpirationDate is the datetime the certificate will expire
 	ExpirationDate time.Time
 
+	// NotYetValid indicates if this certificate is not valid yet, such as a certificate deployed ahead of its start date
+	NotYetValid bool
+
+	// NotBefore is the datetime the certificate becomes valid
+	NotBefore time.Time
+
+	// LifetimeRemainingPercent is the percentage of the certificate's validity period remaining, from 100 before the
+	// certificate is valid down to 0 once it has expired
+	LifetimeRemainingPercent float64
+
 	// Signature is the certificate signature
 	Signature []byte
 
 	// SerialNumber is the Serial Number from the certificate
 	SerialNumber *big.Int
+
+	// Subject is the distinguished name of the certificate subject
+	Subject string
+
+	// Issuer is the distinguished name of the certificate issuer
+	Issuer string
+
+	// DNSNames are the Subject Alternative Names listed within the certificate
+	DNSNames []string
+
+	// SignatureAlgorithm is the algorithm used to sign the certificate
+	SignatureAlgorithm x509.SignatureAlgorithm
+
+	// KeyBits is the size of the certificate's public key in bits, the modulus size for RSA keys and the curve size
+	// for ECDSA keys, zero when the key type is not recognized
+	KeyBits int
+
+	// Fingerprint is the SHA-256 hash of the DER encoded certificate in colon separated uppercase hex, matching
+	// the form shown by browsers and openssl
+	Fingerprint string
+
+	// SelfSigned indicates the certificate's issuer is its own subject and it is signed by its own key
+	SelfSigned bool
+
+	// Role is the certificate's role within the chain, derived from its position and whether it is a self-signed
+	// or CA certificate. The first certificate is the leaf unless it is a CA, self-signed certificates are roots,
+	// and other CA certificates are intermediates.
+	Role Role
+
+	// VerifyError is the result of verifying this certificate against trusted roots using the rest of the chain as
+	// intermediates, the leaf is also checked against the remote system's hostname. A nil value means the certificate
+	// is trusted. This is only populated for certificates fetched from a remote system.
+	VerifyError error
+
+	// HostnameValid indicates the leaf certificate is valid for the remote system's hostname, matching its Subject
+	// Alternative Names without requiring the chain to be trusted. This is only populated for the leaf when
+	// WithVerifyHostname is provided.
+	HostnameValid bool
+
+	// PublicCA indicates the leaf certificate was issued by a public CA rather than a private or internal PKI, as
+	// determined by IsPublicCA or the function provided with WithPublicCAFunc. This is only populated for the leaf of
+	// certificates fetched from a remote system when WithPublicCA or WithPublicCAFunc is provided.
+	PublicCA bool
+
+	// Raw is the complete DER encoded certificate, for archival or parsing with other tools
+	Raw []byte
+
+	// Source is where the certificate was fetched from, the outbound address for remote systems or the file path for
+	// certificates loaded from disk
+	Source string
+
+	// Certificate is the original parsed certificate, for access to details not otherwise exposed
+	Certificate *x509.Certificate
+}
+
+// String will return a concise human-readable summary of the certificate, such as
+// "CN=example.com expires 2025-01-02 (12 days) expired=false".
+func (s CertificateStatus) String() string {
+	return fmt.Sprintf("%s expires %s (%d days) expired=%t", s.Subject, s.ExpirationDate.Format("2006-01-02"), s.ExpiresInDays, s.ExpiredNow)
 }
 
-var dialer = &net.Dialer{
-	Timeout: 3 * time.Second,
+// SerialHex will return the SerialNumber in colon separated uppercase hex, such as 04:A3:1F, matching the form shown
+// by openssl and browsers. A zero serial number is returned as 00, a negative serial number, which is invalid but
+// occasionally seen, is prefixed with a minus sign, and an empty string is returned when there is no serial number.
+func (s CertificateStatus) SerialHex() string {
+	if s.SerialNumber == nil {
+		return ""
+	}
+	b := s.SerialNumber.Bytes()
+	if len(b) == 0 {
+		b = []byte{0}
+	}
+	if s.SerialNumber.Sign() < 0 {
+		return "-" + colonHex(b)
+	}
+	return colonHex(b)
 }
 
 // FetchChain will fetch a remote system's certificate chain and return a CertificateStatus object for each certificate in the chain.
-func FetchChain(address string) ([]*CertificateStatus, error) {
-	conf := &tls.Config{InsecureSkipVerify: true}
-	c, err := tls.DialWithDialer(dialer, "tcp", address, conf)
+// A remote system which completes the handshake without presenting any certificates returns an error wrapping ErrNoCertificates.
+func FetchChain(address string, opts ...Option) ([]*CertificateStatus, error) {
+	return FetchChainContext(context.Background(), address, opts...)
+}
+
+// FetchChainContext is the same as FetchChain but uses the provided context to cancel or set a deadline on the connection and TLS handshake.
+func FetchChainContext(ctx context.Context, address string, opts ...Option) ([]*CertificateStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("Could not establish connection to outbound address %s - %s", address, err)
+		return nil, err
 	}
-	defer c.Close()
+	return filterRoles(chain, newConfig(opts...).roles), nil
+}
+
+// fetchChain will fetch the remote system's complete certificate chain, ignoring WithRoles. Checks use this rather
+// than FetchChainContext as they rely on the leaf being first and followed by its issuer.
+func fetchChain(ctx context.Context, address string, opts ...Option) ([]*CertificateStatus, error) {
+	conn, err := FetchConnectionStatusContext(ctx, address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return conn.Chain, nil
+}
 
+// newChain will build a CertificateStatus for each certificate presented by the remote system, verifying each
+// against the configured roots with the leaf also checked against the remote system's hostname.
+func (cfg *config) newChain(certs []*x509.Certificate, address string) []*CertificateStatus {
 	var chain []*CertificateStatus
-	now := time.Now()
-	for _, cert := range c.ConnectionState().PeerCertificates {
-		status := &CertificateStatus{}
-		// set expiration date
-		status.ExpirationDate = cert.NotAfter
-		// check if currently expired
-		if cert.NotAfter.Before(now) {
-			status.ExpiredNow = true
+	now := cfg.now()
+	for i := range certs {
+		chain = append(chain, cfg.newChainStatus(certs, i, address, now))
+	}
+	return chain
+}
+
+// newChainStatus will build the CertificateStatus for the certificate at the index within the chain presented by the
+// remote system.
+func (cfg *config) newChainStatus(certs []*x509.Certificate, i int, address string, now time.Time) *CertificateStatus {
+	status := newCertificateStatus(certs[i], now)
+	status.Role = certificateRole(certs[i], i)
+	status.Source = address
+	// only the leaf is expected to match the hostname
+	dnsName := ""
+	if i == 0 {
+		dnsName = cfg.hostname(address)
+		if cfg.verifyHostname {
+			status.HostnameValid = certs[i].VerifyHostname(dnsName) == nil
+		}
+		if cfg.publicCA != nil {
+			status.PublicCA = cfg.publicCA(certs)
+		}
+	}
+	status.VerifyError = verifyChain(certs[i:], dnsName, cfg.rootCAs, now)
+	return status
+}
+
+// fetchState will connect to the remote system and return the state of the completed TLS connection, retrying
+// transient failures when WithRetries is configured.
+func (cfg *config) fetchState(ctx context.Context, address string) (tls.ConnectionState, error) {
+	backoff := cfg.retryBackoff
+	for attempt := 0; ; attempt++ {
+		c, err := cfg.connect(ctx, address)
+		if err == nil {
+			defer c.Close()
+			state := c.ConnectionState()
+			if err := cfg.checkState(state, address); err != nil {
+				return tls.ConnectionState{}, err
+			}
+			return state, nil
+		}
+		if ctx.Err() != nil {
+			return tls.ConnectionState{}, fmt.Errorf("Connection to outbound address %s cancelled - %w", address, ctx.Err())
+		}
+		if attempt >= cfg.retries || !retryable(err) {
+			return tls.ConnectionState{}, err
 		}
-		// extract number of days until expiration
-		status.ExpiresInDays = int(cert.NotAfter.Sub(now).Hours() / 24)
-		// grab certificate details for identification
-		status.Signature = cert.Signature
-		status.SerialNumber = cert.SerialNumber
-		chain = append(chain, status)
+
+		// Wait before retrying, giving up early if the context is done
+		select {
+		case <-ctx.Done():
+			return tls.ConnectionState{}, fmt.Errorf("Connection to outbound address %s cancelled - %w", address, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// daysUntil will return the number of whole days from now until t. Times in the past are rounded down, so a time
+// earlier today returns -1 and a time 12 and a half days ago returns -13.
+func daysUntil(t, now time.Time) int {
+	return int(math.Floor(t.Sub(now).Hours() / 24))
+}
+
+// fingerprint will return the SHA-256 hash of the data in colon separated uppercase hex, such as AB:CD:EF.
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return colonHex(sum[:])
+}
+
+// colonHex will return the data in colon separated uppercase hex, such as AB:CD:EF.
+func colonHex(data []byte) string {
+	parts := make([]string, len(data))
+	for i, b := range data {
+		parts[i] = fmt.Sprintf("%02X", b)
 	}
-	return chain, nil
+	return strings.Join(parts, ":")
 }
 
-// Expired indicates whether there is an expired certificate within the remote system's certificate chain.
-func Expired(address string) (bool, error) {
-	chain, err := FetchChain(address)
+// checkState will return an error if the completed handshake presented no certificates, presented more than the
+// maximum chain depth, or presented certificates that do not match the pinned public keys.
+func (cfg *config) checkState(state tls.ConnectionState, address string) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	if cfg.maxChainDepth > 0 && len(state.PeerCertificates) > cfg.maxChainDepth {
+		return fmt.Errorf("%w, outbound address %s presented %d certificates exceeding the maximum of %d", ErrChainTooLong, address, len(state.PeerCertificates), cfg.maxChainDepth)
+	}
+	return cfg.checkPins(state.PeerCertificates, address)
+}
+
+// lifetimeRemaining will return the percentage of the validity period from notBefore to notAfter remaining at now,
+// clamped between 0 and 100.
+func lifetimeRemaining(notBefore, notAfter, now time.Time) float64 {
+	lifetime := notAfter.Sub(notBefore)
+	if lifetime <= 0 {
+		return 0
+	}
+	pct := float64(notAfter.Sub(now)) / float64(lifetime) * 100
+	return math.Max(0, math.Min(100, pct))
+}
+
+// newCertificateStatus will build a CertificateStatus from the provided certificate relative to the provided time.
+func newCertificateStatus(cert *x509.Certificate, now time.Time) *CertificateStatus {
+	status := &CertificateStatus{}
+	// set expiration date
+	status.ExpirationDate = cert.NotAfter
+	// check if currently expired
+	if cert.NotAfter.Before(now) {
+		status.ExpiredNow = true
+	}
+	// check if not valid yet
+	status.NotBefore = cert.NotBefore
+	if cert.NotBefore.After(now) {
+		status.NotYetValid = true
+	}
+	// extract number of days until expiration
+	status.ExpiresInDays = daysUntil(cert.NotAfter, now)
+	status.LifetimeRemainingPercent = lifetimeRemaining(cert.NotBefore, cert.NotAfter, now)
+	// grab certificate details for identification
+	status.Signature = cert.Signature
+	status.SerialNumber = cert.SerialNumber
+	status.Subject = cert.Subject.String()
+	status.Issuer = cert.Issuer.String()
+	status.DNSNames = cert.DNSNames
+	status.SignatureAlgorithm = cert.SignatureAlgorithm
+	status.KeyBits = keyBits(cert.PublicKey)
+	status.Fingerprint = fingerprint(cert.Raw)
+	status.Raw = cert.Raw
+	status.SelfSigned = selfSigned(cert)
+	status.Certificate = cert
+	return status
+}
+
+// Expired indicates whether there is an expired certificate within the remote system's certificate chain. When the
+// chain cannot be fetched Expired fails closed, returning true along with the error, so an unreachable remote system
+// is reported as expired to callers which ignore the error. Use CheckExpired to tell the two apart.
+func Expired(address string, opts ...Option) (bool, error) {
+	return ExpiredContext(context.Background(), address, opts...)
+}
+
+// ExpiredContext is the same as Expired but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiredContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpired(chain), nil
+}
+
+// NotYetValid will return true if a certificate within the remote system's certificate chain is not valid yet, the
+// mirror image of expiry often caused by clock skew or deploying a certificate before its start date.
+func NotYetValid(address string, opts ...Option) (bool, error) {
+	return NotYetValidContext(context.Background(), address, opts...)
+}
+
+// NotYetValidContext is the same as NotYetValid but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func NotYetValidContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	chain, err := fetchChain(ctx, address, opts...)
 	if err != nil {
-		return true, fmt.Errorf("Error Fetching Certificate Chain - %s", err)
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
 	}
 	for _, cert := range chain {
-		if cert.ExpiredNow {
+		if cert.NotYetValid {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
-// ExpiresWithinDays will return true if a certificate within the remote system's certificate chain expires within the specified number of days.
-func ExpiresWithinDays(address string, days int) (bool, error) {
-	chain, err := FetchChain(address)
+// ExpiresWithinDays will return true if a certificate within the remote system's certificate chain expires within the
+// specified number of days. The comparison is exclusive, a certificate with exactly days whole days remaining is not
+// flagged unless WithInclusiveDays is provided.
+func ExpiresWithinDays(address string, days int, opts ...Option) (bool, error) {
+	return ExpiresWithinDaysContext(context.Background(), address, days, opts...)
+}
+
+// ExpiresWithinDaysContext is the same as ExpiresWithinDays but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiresWithinDaysContext(ctx context.Context, address string, days int, opts ...Option) (bool, error) {
+	chain, err := fetchChain(ctx, address, opts...)
 	if err != nil {
-		return true, fmt.Errorf("Error Fetching Certificate Chain - %s", err)
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpiresWithinDays(chain, days, newConfig(opts...).inclusiveDays), nil
+}
+
+// ExpiresWithinPercent will return true if a certificate within the remote system's certificate chain has less than
+// the specified percentage of its validity period remaining, such as 20 for a 90 day certificate with 18 days left.
+// This adapts the threshold to both short and long lived certificates. Expired certificates have 0 percent remaining.
+func ExpiresWithinPercent(address string, pct float64, opts ...Option) (bool, error) {
+	return ExpiresWithinPercentContext(context.Background(), address, pct, opts...)
+}
+
+// ExpiresWithinPercentContext is the same as ExpiresWithinPercent but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiresWithinPercentContext(ctx context.Context, address string, pct float64, opts ...Option) (bool, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
 	}
 	for _, cert := range chain {
-		if cert.ExpiresInDays < days {
+		if cert.LifetimeRemainingPercent < pct {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
+// ExpiresWithin will return true if a certificate within the remote system's certificate chain expires within the
+// specified duration from now. The comparison is made directly against each certificate's expiration date rather
+// than a count of whole days, and is exclusive, a certificate expiring exactly at the end of the duration is not
+// considered to expire within it.
+func ExpiresWithin(address string, d time.Duration, opts ...Option) (bool, error) {
+	return ExpiresWithinContext(context.Background(), address, d, opts...)
+}
+
+// ExpiresWithinContext is the same as ExpiresWithin but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiresWithinContext(ctx context.Context, address string, d time.Duration, opts ...Option) (bool, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpiresBefore(chain, newConfig(opts...).now().Add(d)), nil
+}
+
 // ExpiresBeforeDate will return true if a certificate within the remote system's certificate chain expires before the specified date.
-func ExpiresBeforeDate(address string, t time.Time) (bool, error) {
-	chain, err := FetchChain(address)
+func ExpiresBeforeDate(address string, t time.Time, opts ...Option) (bool, error) {
+	return ExpiresBeforeDateContext(context.Background(), address, t, opts...)
+}
+
+// ExpiresBeforeDateContext is the same as ExpiresBeforeDate but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiresBeforeDateContext(ctx context.Context, address string, t time.Time, opts ...Option) (bool, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return anyExpiresBefore(chain, t), nil
+}
+
+// fetchLeaf will fetch a remote system's certificate chain and return the leaf (end-entity) certificate presented first in the chain.
+func fetchLeaf(ctx context.Context, address string, opts ...Option) (*CertificateStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	return chain[0], nil
+}
+
+// SplitChain will fetch the remote system's certificate chain and return the leaf (end-entity) certificate
+// separately from the intermediate and root certificates that follow it. The intermediates are empty, not nil, when
+// the remote system presents only a leaf.
+func SplitChain(address string, opts ...Option) (*CertificateStatus, []*CertificateStatus, error) {
+	return SplitChainContext(context.Background(), address, opts...)
+}
+
+// SplitChainContext is the same as SplitChain but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func SplitChainContext(ctx context.Context, address string, opts ...Option) (*CertificateStatus, []*CertificateStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
 	if err != nil {
-		return true, fmt.Errorf("Error Fetching Certificate Chain - %s", err)
+		return nil, nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
 	}
+	if len(chain) == 0 {
+		return nil, nil, fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	return chain[0], append([]*CertificateStatus{}, chain[1:]...), nil
+}
+
+// LeafExpired indicates whether the remote system's leaf certificate is expired, ignoring any intermediate or root certificates in the chain.
+func LeafExpired(address string, opts ...Option) (bool, error) {
+	return LeafExpiredContext(context.Background(), address, opts...)
+}
+
+// LeafExpiredContext is the same as LeafExpired but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func LeafExpiredContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	leaf, err := fetchLeaf(ctx, address, opts...)
+	if err != nil {
+		return true, err
+	}
+	return leaf.ExpiredNow, nil
+}
+
+// LeafExpiresWithinDays will return true if the remote system's leaf certificate expires within the specified number of days, ignoring any intermediate or root certificates in the chain.
+func LeafExpiresWithinDays(address string, days int, opts ...Option) (bool, error) {
+	return LeafExpiresWithinDaysContext(context.Background(), address, days, opts...)
+}
+
+// LeafExpiresWithinDaysContext is the same as LeafExpiresWithinDays but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func LeafExpiresWithinDaysContext(ctx context.Context, address string, days int, opts ...Option) (bool, error) {
+	leaf, err := fetchLeaf(ctx, address, opts...)
+	if err != nil {
+		return true, err
+	}
+	return withinDays(leaf, days, newConfig(opts...).inclusiveDays), nil
+}
+
+// DaysUntilExpiry will return the number of days until the remote system's leaf certificate expires. A certificate
+// which has already expired returns a negative value, a certificate that expired earlier today returns -1.
+func DaysUntilExpiry(address string, opts ...Option) (int, error) {
+	return DaysUntilExpiryContext(context.Background(), address, opts...)
+}
+
+// DaysUntilExpiryContext is the same as DaysUntilExpiry but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func DaysUntilExpiryContext(ctx context.Context, address string, opts ...Option) (int, error) {
+	leaf, err := fetchLeaf(ctx, address, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return leaf.ExpiresInDays, nil
+}
+
+// IsSelfSigned will return true if the remote system's leaf certificate is self-signed, which often indicates a
+// development certificate deployed by mistake.
+func IsSelfSigned(address string, opts ...Option) (bool, error) {
+	return IsSelfSignedContext(context.Background(), address, opts...)
+}
+
+// IsSelfSignedContext is the same as IsSelfSigned but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func IsSelfSignedContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	leaf, err := fetchLeaf(ctx, address, opts...)
+	if err != nil {
+		return false, err
+	}
+	return leaf.SelfSigned, nil
+}
+
+// selfSigned will return true if the certificate's issuer matches its subject and its signature is valid for its own public key.
+func selfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// ExpiresSoonest will return the certificate within the remote system's certificate chain that expires first,
+// including intermediate and root certificates. When multiple certificates share the earliest expiration date the
+// first one encountered in the chain is returned.
+func ExpiresSoonest(address string, opts ...Option) (*CertificateStatus, error) {
+	return ExpiresSoonestContext(context.Background(), address, opts...)
+}
+
+// ExpiresSoonestContext is the same as ExpiresSoonest but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiresSoonestContext(ctx context.Context, address string, opts ...Option) (*CertificateStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	soonest := SoonestExpiry(chain)
+	if soonest == nil {
+		return nil, fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	return soonest, nil
+}
+
+// TimeUntilExpiry will return the exact time remaining until the soonest expiring certificate within the remote
+// system's certificate chain expires, for alert thresholds and countdowns finer than whole days. The duration is
+// negative when a certificate has already expired, reporting how long ago it expired. Zero is returned along with an
+// error when the chain cannot be fetched.
+func TimeUntilExpiry(address string, opts ...Option) (time.Duration, error) {
+	return TimeUntilExpiryContext(context.Background(), address, opts...)
+}
+
+// TimeUntilExpiryContext is the same as TimeUntilExpiry but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func TimeUntilExpiryContext(ctx context.Context, address string, opts ...Option) (time.Duration, error) {
+	soonest, err := ExpiresSoonestContext(ctx, address, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return soonest.ExpirationDate.Sub(newConfig(opts...).now()), nil
+}
+
+// ExpiringCerts will return only the certificates within the remote system's certificate chain which expire within
+// the specified duration from now, including certificates which have already expired. An empty slice is returned
+// when nothing within the chain is expiring.
+func ExpiringCerts(address string, within time.Duration, opts ...Option) ([]*CertificateStatus, error) {
+	return ExpiringCertsContext(context.Background(), address, within, opts...)
+}
+
+// ExpiringCertsContext is the same as ExpiringCerts but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiringCertsContext(ctx context.Context, address string, within time.Duration, opts ...Option) ([]*CertificateStatus, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return expiringBefore(chain, newConfig(opts...).now().Add(within)), nil
+}
+
+// ExpiresBetween will return true if a certificate within the remote system's certificate chain expires within the
+// window from start, inclusive, to end, exclusive. This supports planning renewals for a future window, such as
+// between 20 and 40 days from now. An error is returned if start is not before end.
+func ExpiresBetween(address string, start, end time.Time, opts ...Option) (bool, error) {
+	return ExpiresBetweenContext(context.Background(), address, start, end, opts...)
+}
+
+// ExpiresBetweenContext is the same as ExpiresBetween but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiresBetweenContext(ctx context.Context, address string, start, end time.Time, opts ...Option) (bool, error) {
+	certs, err := ExpiringBetweenContext(ctx, address, start, end, opts...)
+	if err != nil {
+		return true, err
+	}
+	return len(certs) > 0, nil
+}
+
+// ExpiringBetween is the same as ExpiresBetween but returns the certificates within the remote system's certificate
+// chain which expire within the window. An empty slice is returned when no certificate expires within the window.
+func ExpiringBetween(address string, start, end time.Time, opts ...Option) ([]*CertificateStatus, error) {
+	return ExpiringBetweenContext(context.Background(), address, start, end, opts...)
+}
+
+// ExpiringBetweenContext is the same as ExpiringBetween but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ExpiringBetweenContext(ctx context.Context, address string, start, end time.Time, opts ...Option) ([]*CertificateStatus, error) {
+	if !start.Before(end) {
+		return nil, fmt.Errorf("Invalid window, start %s must be before end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return expiringBetween(chain, start, end), nil
+}
+
+// expiringBetween will return the certificates within the chain which expire at or after start and before end, in chain order.
+func expiringBetween(chain []*CertificateStatus, start, end time.Time) []*CertificateStatus {
+	expiring := []*CertificateStatus{}
+	for _, cert := range chain {
+		if !cert.ExpirationDate.Before(start) && cert.ExpirationDate.Before(end) {
+			expiring = append(expiring, cert)
+		}
+	}
+	return expiring
+}
+
+// expiringBefore will return the certificates within the chain which expire before the time, in chain order.
+func expiringBefore(chain []*CertificateStatus, t time.Time) []*CertificateStatus {
+	expiring := []*CertificateStatus{}
 	for _, cert := range chain {
 		if cert.ExpirationDate.Before(t) {
-			return true, nil
+			expiring = append(expiring, cert)
 		}
 	}
-	return false, nil
+	return expiring
+}
+
+// SoonestExpiry will return the first certificate with the earliest expiration date, or nil for an empty chain. This
+// is useful with certificates aggregated from many sources, such as FetchChainFromDir.
+func SoonestExpiry(chain []*CertificateStatus) *CertificateStatus {
+	var soonest *CertificateStatus
+	for _, cert := range chain {
+		if soonest == nil || cert.ExpirationDate.Before(soonest.ExpirationDate) {
+			soonest = cert
+		}
+	}
+	return soonest
+}
+
+// anyExpired will return true if any certificate within the chain is expired.
+func anyExpired(chain []*CertificateStatus) bool {
+	for _, cert := range chain {
+		if cert.ExpiredNow {
+			return true
+		}
+	}
+	return false
+}
+
+// withinDays will return true if the certificate expires within the number of days, including the boundary day when inclusive.
+func withinDays(cert *CertificateStatus, days int, inclusive bool) bool {
+	if inclusive {
+		return cert.ExpiresInDays <= days
+	}
+	return cert.ExpiresInDays < days
+}
+
+// anyExpiresWithinDays will return true if any certificate within the chain expires within the number of days.
+func anyExpiresWithinDays(chain []*CertificateStatus, days int, inclusive bool) bool {
+	for _, cert := range chain {
+		if withinDays(cert, days, inclusive) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyExpiresBefore will return true if any certificate within the chain expires before the time.
+func anyExpiresBefore(chain []*CertificateStatus, t time.Time) bool {
+	for _, cert := range chain {
+		if cert.ExpirationDate.Before(t) {
+			return true
+		}
+	}
+	return false
 }
@@ -1,13 +1,18 @@
 package hazexpired
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"net"
 	"testing"
@@ -21,7 +26,9 @@ func genCerts(date time.Time) ([]byte, []byte, error) {
 	ca := &x509.Certificate{
 		Subject: pkix.Name{
 			Organization: []string{"I Can Haz Expired Certs"},
+			CommonName:   "localhost",
 		},
+		DNSNames:              []string{"localhost"},
 		SerialNumber:          big.NewInt(42),
 		NotBefore:             date.Truncate(8760 * time.Hour),
 		NotAfter:              date,
@@ -50,6 +57,90 @@ func genCerts(date time.Time) ([]byte, []byte, error) {
 	return c, k, nil
 }
 
+// testChain holds a leaf certificate signed by a test CA for tests that need a multi-certificate chain
+type testChain struct {
+	// ca is the issuing certificate
+	ca *x509.Certificate
+
+	// caKey is the issuing certificate's private key
+	caKey *ecdsa.PrivateKey
+
+	// leaf is the certificate signed by ca
+	leaf *x509.Certificate
+
+	// cert is the leaf and ca chain with the leaf's private key, ready to be served
+	cert tls.Certificate
+}
+
+// genChain is a test case helper that will create a CA and a leaf certificate signed by it with the specified
+// expiration date, customize can modify the leaf template before it is signed
+//
+//	chain, err := genChain(time.Now().Add(900 * time.Hour), nil)
+func genChain(date time.Time, customize func(leaf *x509.Certificate)) (*testChain, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("Could not generate ecdsa key - %s", err)
+	}
+	caTemplate := &x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{"I Can Haz Expired Certs"},
+			CommonName:   "I Can Haz Expired Certs CA",
+		},
+		SerialNumber:          big.NewInt(42),
+		NotBefore:             date.Add(-8760 * time.Hour),
+		NotAfter:              date.Add(8760 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("Could not generate CA certificate - %s", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse CA certificate - %s", err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("Could not generate ecdsa key - %s", err)
+	}
+	leafTemplate := &x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{"I Can Haz Expired Certs"},
+			CommonName:   "localhost",
+		},
+		DNSNames:     []string{"localhost"},
+		SerialNumber: big.NewInt(43),
+		NotBefore:    date.Add(-8760 * time.Hour),
+		NotAfter:     date,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if customize != nil {
+		customize(leafTemplate)
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("Could not generate leaf certificate - %s", err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse leaf certificate - %s", err)
+	}
+
+	return &testChain{
+		ca:    ca,
+		caKey: caKey,
+		leaf:  leaf,
+		cert: tls.Certificate{
+			Certificate: [][]byte{leafDER, caDER},
+			PrivateKey:  leafKey,
+		},
+	}, nil
+}
+
 // startListener will start a TLS listener and return the listener which can be used for control actions like l.Close()
 func startListener(cert, key []byte) (net.Listener, error) {
 	// Load the cert and key into a tls.Config{}
@@ -57,10 +148,13 @@ func startListener(cert, key []byte) (net.Listener, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Could not start test listener - %s", err)
 	}
-	conf := tls.Config{Certificates: []tls.Certificate{certs}}
+	return startConfigListener(&tls.Config{Certificates: []tls.Certificate{certs}})
+}
 
+// startConfigListener will start a TLS listener using the provided tls.Config, allowing tests to customize server behavior
+func startConfigListener(conf *tls.Config) (net.Listener, error) {
 	// Start the tls listener
-	l, err := tls.Listen("tcp", "0.0.0.0:9000", &conf)
+	l, err := tls.Listen("tcp", "0.0.0.0:9000", conf)
 	if err != nil {
 		return nil, fmt.Errorf("Could not start test listener - %s", err)
 	}
@@ -107,12 +201,68 @@ func TestInvalidAddress(t *testing.T) {
 		}
 	})
 
+	t.Run("ExpiresWithin", func(t *testing.T) {
+		_, err := ExpiresWithin("iamateapot:418", time.Hour)
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+
 	t.Run("ExpiresBeforeDate", func(t *testing.T) {
 		_, err := ExpiresBeforeDate("iamateapot:418", time.Now())
 		if err == nil {
 			t.Errorf("Expected failure when calling with an invalid address, err is nil")
 		}
 	})
+
+	t.Run("IsSelfSigned", func(t *testing.T) {
+		_, err := IsSelfSigned("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+
+	t.Run("LeafExpired", func(t *testing.T) {
+		_, err := LeafExpired("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+
+	t.Run("LeafExpiresWithinDays", func(t *testing.T) {
+		_, err := LeafExpiresWithinDays("iamateapot:418", 30)
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+
+	t.Run("DaysUntilExpiry", func(t *testing.T) {
+		_, err := DaysUntilExpiry("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+
+	t.Run("SplitChain", func(t *testing.T) {
+		_, _, err := SplitChain("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+
+	t.Run("ExpiresWithinPercent", func(t *testing.T) {
+		_, err := ExpiresWithinPercent("iamateapot:418", 20)
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+
+	t.Run("ExpiringCerts", func(t *testing.T) {
+		_, err := ExpiringCerts("iamateapot:418", time.Hour)
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
 }
 
 // Test with a valid Address/Port and valid certificate chain
@@ -144,6 +294,28 @@ func TestHappyPathGoodCert(t *testing.T) {
 			if cert.ExpiredNow != false {
 				t.Errorf("Unexpected expired certificate found - %+v", cert)
 			}
+			if cert.Fingerprint != fingerprint(cert.Certificate.Raw) {
+				t.Errorf("Unexpected fingerprint for certificate got %s", cert.Fingerprint)
+			}
+		}
+	})
+
+	t.Run("CertificateDetails", func(t *testing.T) {
+		chain, err := FetchChain("127.0.0.1:9000")
+		if err != nil || len(chain) == 0 {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if chain[0].Subject != "CN=localhost,O=I Can Haz Expired Certs" {
+			t.Errorf("Unexpected Subject on certificate got %s", chain[0].Subject)
+		}
+		if chain[0].Issuer != chain[0].Subject {
+			t.Errorf("Unexpected Issuer on self-signed certificate got %s", chain[0].Issuer)
+		}
+		if len(chain[0].DNSNames) != 1 || chain[0].DNSNames[0] != "localhost" {
+			t.Errorf("Unexpected DNSNames on certificate got %+v", chain[0].DNSNames)
+		}
+		if chain[0].Certificate == nil || !chain[0].Certificate.NotAfter.Equal(chain[0].ExpirationDate) {
+			t.Errorf("Unexpected parsed Certificate on certificate got %+v", chain[0].Certificate)
 		}
 	})
 
@@ -179,6 +351,46 @@ func TestHappyPathGoodCert(t *testing.T) {
 			t.Errorf("Unexpected result when testing ExpiredsBeforeDate on happy path expected false got %+v", v)
 		}
 	})
+
+	t.Run("LeafExpired", func(t *testing.T) {
+		v, err := LeafExpired("127.0.0.1:9000")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling LeafExpired - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing LeafExpired on happy path expected false got %+v", v)
+		}
+	})
+
+	t.Run("LeafExpiresWithinDays", func(t *testing.T) {
+		v, err := LeafExpiresWithinDays("127.0.0.1:9000", 30)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling LeafExpiresWithinDays - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing LeafExpiresWithinDays on happy path expected false got %+v", v)
+		}
+	})
+
+	t.Run("DaysUntilExpiry", func(t *testing.T) {
+		v, err := DaysUntilExpiry("127.0.0.1:9000")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling DaysUntilExpiry - %s", err)
+		}
+		if v != 37 {
+			t.Errorf("Unexpected result when testing DaysUntilExpiry on happy path expected 37 got %d", v)
+		}
+	})
+
+	t.Run("IsSelfSigned", func(t *testing.T) {
+		v, err := IsSelfSigned("127.0.0.1:9000")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling IsSelfSigned - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing IsSelfSigned with a self-signed cert expected true got %+v", v)
+		}
+	})
 }
 
 // Test with a valid Address/Port and expired certificate
@@ -249,6 +461,46 @@ func TestHappyPathExpiredCert(t *testing.T) {
 			t.Errorf("Unexpected result when testing ExpiredsBeforeDate on happy path expected true got %+v", v)
 		}
 	})
+
+	t.Run("LeafExpired", func(t *testing.T) {
+		v, err := LeafExpired("127.0.0.1:9000")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling LeafExpired - %s", err)
+		}
+		if v == false {
+			t.Errorf("Unexpected result when testing LeafExpired on happy path expected true got %+v", v)
+		}
+	})
+
+	t.Run("LeafExpiresWithinDays", func(t *testing.T) {
+		v, err := LeafExpiresWithinDays("127.0.0.1:9000", 30)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling LeafExpiresWithinDays - %s", err)
+		}
+		if v == false {
+			t.Errorf("Unexpected result when testing LeafExpiresWithinDays on happy path expected true got %+v", v)
+		}
+	})
+
+	t.Run("DaysUntilExpiry", func(t *testing.T) {
+		v, err := DaysUntilExpiry("127.0.0.1:9000")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling DaysUntilExpiry - %s", err)
+		}
+		if v != -1 {
+			t.Errorf("Unexpected result when testing DaysUntilExpiry on an expired cert expected -1 got %d", v)
+		}
+	})
+
+	t.Run("ExpiresSoonest", func(t *testing.T) {
+		cert, err := ExpiresSoonest("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling ExpiresSoonest - %s", err)
+		}
+		if !cert.ExpiredNow {
+			t.Errorf("Unexpected result when testing ExpiresSoonest on happy path expected an expired cert got %+v", cert)
+		}
+	})
 }
 
 // Test a certificate that is expiring soon
@@ -281,6 +533,33 @@ func TestExpiringCert(t *testing.T) {
 		}
 	})
 
+	// Test the exact day boundary, the cert has 14 whole days remaining
+	t.Run("ExpiresWithinDaysBoundary", func(t *testing.T) {
+		v, err := ExpiresWithinDays("127.0.0.1:9000", 14)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiresWithinDays - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing ExpiresWithinDays 14 with a cert that has 14 days remaining, expected false got %+v", v)
+		}
+
+		v, err = ExpiresWithinDays("127.0.0.1:9000", 14, WithInclusiveDays())
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiresWithinDays - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing inclusive ExpiresWithinDays 14 with a cert that has 14 days remaining, expected true got %+v", v)
+		}
+
+		v, err = LeafExpiresWithinDays("127.0.0.1:9000", 14, WithInclusiveDays())
+		if err != nil {
+			t.Errorf("Unexpected failure when calling LeafExpiresWithinDays - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing inclusive LeafExpiresWithinDays 14 with a cert that has 14 days remaining, expected true got %+v", v)
+		}
+	})
+
 	// Test if it expires by x date
 	t.Run("ExpiresBeforeDate", func(t *testing.T) {
 		var v bool
@@ -292,4 +571,535 @@ func TestExpiringCert(t *testing.T) {
 			t.Errorf("Unexpected result when testing ExpiredsBeforeDate with a cert that expires in 15 days, expected true got %+v", v)
 		}
 	})
+
+	// Test returning only the expiring certificates
+	t.Run("ExpiringCerts", func(t *testing.T) {
+		certs, err := ExpiringCerts("127.0.0.1:9000", 720*time.Hour)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiringCerts - %s", err)
+		}
+		if len(certs) != 1 {
+			t.Errorf("Unexpected result when testing ExpiringCerts with a cert that expires in 15 days, expected 1 cert got %d", len(certs))
+		}
+
+		certs, err = ExpiringCerts("127.0.0.1:9000", 24*time.Hour)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiringCerts - %s", err)
+		}
+		if len(certs) != 0 {
+			t.Errorf("Unexpected result when testing ExpiringCerts within 1 day with a cert that expires in 15 days, expected 0 certs got %d", len(certs))
+		}
+	})
+
+	// Test if it expires within a percentage of its lifetime
+	t.Run("ExpiresWithinPercent", func(t *testing.T) {
+		chain, err := FetchChain("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		pct := chain[0].LifetimeRemainingPercent
+		if pct <= 0 || pct >= 100 {
+			t.Fatalf("Unexpected lifetime remaining for a valid cert got %.2f", pct)
+		}
+
+		v, err := ExpiresWithinPercent("127.0.0.1:9000", pct+1)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiresWithinPercent - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing ExpiresWithinPercent %.2f with a cert that has %.2f percent remaining, expected true got %+v", pct+1, pct, v)
+		}
+
+		v, err = ExpiresWithinPercent("127.0.0.1:9000", pct/2)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiresWithinPercent - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing ExpiresWithinPercent %.2f with a cert that has %.2f percent remaining, expected false got %+v", pct/2, pct, v)
+		}
+	})
+
+	// Test if it expires within a future window
+	t.Run("ExpiresBetween", func(t *testing.T) {
+		v, err := ExpiresBetween("127.0.0.1:9000", time.Now().Add(240*time.Hour), time.Now().Add(480*time.Hour))
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiresBetween - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing ExpiresBetween 10 and 20 days with a cert that expires in 15 days, expected true got %+v", v)
+		}
+
+		certs, err := ExpiringBetween("127.0.0.1:9000", time.Now().Add(480*time.Hour), time.Now().Add(960*time.Hour))
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiringBetween - %s", err)
+		}
+		if len(certs) != 0 {
+			t.Errorf("Unexpected result when testing ExpiringBetween 20 and 40 days with a cert that expires in 15 days, expected 0 certs got %d", len(certs))
+		}
+
+		_, err = ExpiresBetween("127.0.0.1:9000", time.Now().Add(480*time.Hour), time.Now().Add(240*time.Hour))
+		if err == nil {
+			t.Errorf("Expected failure when calling ExpiresBetween with start after end, err is nil")
+		}
+	})
+
+	// Test if it expires within a precise duration
+	t.Run("ExpiresWithin", func(t *testing.T) {
+		v, err := ExpiresWithin("127.0.0.1:9000", 361*time.Hour)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiresWithin - %s", err)
+		}
+		if v == false {
+			t.Errorf("Unexpected result when testing ExpiresWithin 361 hours with a cert that expires in 360 hours, expected true got %+v", v)
+		}
+
+		v, err = ExpiresWithin("127.0.0.1:9000", 359*time.Hour)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling ExpiresWithin - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing ExpiresWithin 359 hours with a cert that expires in 360 hours, expected false got %+v", v)
+		}
+	})
+}
+
+// Test that a cancelled context or timeout stops a hanging TLS handshake
+func TestHangingHandshake(t *testing.T) {
+	// Start a plain TCP listener which never completes a TLS handshake
+	l, err := net.Listen("tcp", "0.0.0.0:9000")
+	if err != nil {
+		t.Logf("Could not start test listener - %s", err)
+		t.FailNow()
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	t.Run("FetchChainContext", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := FetchChainContext(ctx, "127.0.0.1:9000")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context deadline error when handshake hangs, got %s", err)
+		}
+	})
+
+	t.Run("ExpiredContext", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := ExpiredContext(ctx, "127.0.0.1:9000")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context cancelled error, got %s", err)
+		}
+	})
+	t.Run("WithTimeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := FetchChain("127.0.0.1:9000", WithTimeout(100*time.Millisecond))
+		if err == nil {
+			t.Errorf("Expected failure when handshake hangs past the timeout, err is nil")
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected handshake to be abandoned after 100ms, took %s", time.Since(start))
+		}
+	})
+
+	t.Run("WithHandshakeTimeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := FetchChain("127.0.0.1:9000", WithTimeout(10*time.Second), WithHandshakeTimeout(100*time.Millisecond))
+		var handshakeErr *HandshakeError
+		if !errors.As(err, &handshakeErr) || !errors.Is(err, ErrHandshakeTimeout) {
+			t.Errorf("Expected HandshakeError wrapping ErrHandshakeTimeout when handshake hangs, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected handshake to be abandoned after 100ms, took %s", time.Since(start))
+		}
+	})
+}
+
+// Test that the expected SNI hostname is sent during the handshake
+func TestServerName(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+	certs, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Logf("Unable to load test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener which records the SNI hostname of each handshake
+	sni := make(chan string, 1)
+	l, err := startConfigListener(&tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			sni <- hello.ServerName
+			return &certs, nil
+		},
+	})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	tt := []struct {
+		name    string
+		address string
+		opts    []Option
+		sni     string
+	}{
+		{"IPLiteral", "127.0.0.1:9000", nil, ""},
+		{"Hostname", "localhost:9000", nil, "localhost"},
+		{"WithServerName", "127.0.0.1:9000", []Option{WithServerName("example.com")}, "example.com"},
+	}
+
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := FetchChain(c.address, c.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+			}
+			if v := <-sni; v != c.sni {
+				t.Errorf("Unexpected SNI hostname sent, expected %q got %q", c.sni, v)
+			}
+		})
+	}
+}
+
+// Test formatting the serial number in colon separated hex
+func TestSerialHex(t *testing.T) {
+	negative, _ := new(big.Int).SetString("-4096", 10)
+	large, _ := new(big.Int).SetString("04A31F0000000000000000000000000000FF", 16)
+	tt := []struct {
+		name   string
+		serial *big.Int
+		want   string
+	}{
+		{"Small", big.NewInt(42), "2A"},
+		{"MultiByte", big.NewInt(4096), "10:00"},
+		{"Large", large, "04:A3:1F:00:00:00:00:00:00:00:00:00:00:00:00:00:00:FF"},
+		{"Zero", big.NewInt(0), "00"},
+		{"Negative", negative, "-10:00"},
+		{"Nil", nil, ""},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			status := CertificateStatus{SerialNumber: c.serial}
+			if v := status.SerialHex(); v != c.want {
+				t.Errorf("Unexpected SerialHex, expected %q got %q", c.want, v)
+			}
+		})
+	}
+}
+
+// Test the human-readable summary of a CertificateStatus
+func TestCertificateStatusString(t *testing.T) {
+	status := &CertificateStatus{
+		Subject:        "CN=example.com",
+		ExpirationDate: time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC),
+		ExpiresInDays:  12,
+		Signature:      []byte{0xde, 0xad, 0xbe, 0xef},
+	}
+	want := "CN=example.com expires 2025-01-02 (12 days) expired=false"
+	if v := status.String(); v != want {
+		t.Errorf("Unexpected String result, expected %q got %q", want, v)
+	}
+	if v := fmt.Sprintf("%+v", status); v != want {
+		t.Errorf("Unexpected formatted result, expected %q got %q", want, v)
+	}
+}
+
+// Test the percentage of a certificate's lifetime remaining
+func TestLifetimeRemaining(t *testing.T) {
+	now := time.Now()
+	tt := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		pct       float64
+	}{
+		{"Halfway", now.Add(-45 * 24 * time.Hour), now.Add(45 * 24 * time.Hour), 50},
+		{"TwentyPercent", now.Add(-72 * 24 * time.Hour), now.Add(18 * 24 * time.Hour), 20},
+		{"Expired", now.Add(-90 * 24 * time.Hour), now.Add(-time.Hour), 0},
+		{"NotYetValid", now.Add(time.Hour), now.Add(90 * 24 * time.Hour), 100},
+		{"NoLifetime", now, now, 0},
+	}
+
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			if v := lifetimeRemaining(c.notBefore, c.notAfter, now); math.Abs(v-c.pct) > 0.001 {
+				t.Errorf("Unexpected lifetime remaining, expected %.2f got %.2f", c.pct, v)
+			}
+		})
+	}
+}
+
+// Test formatting a SHA-256 fingerprint
+func TestFingerprint(t *testing.T) {
+	// sha256 of "hello"
+	want := "2C:F2:4D:BA:5F:B0:A3:0E:26:E8:3B:2A:C5:B9:E2:9E:1B:16:1E:5C:1F:A7:42:5E:73:04:33:62:93:8B:98:24"
+	if v := fingerprint([]byte("hello")); v != want {
+		t.Errorf("Unexpected fingerprint, expected %s got %s", want, v)
+	}
+}
+
+// Test a certificate that is not valid yet
+func TestNotYetValidCert(t *testing.T) {
+	// Create a chain with a leaf valid from tomorrow
+	chain, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.NotBefore = time.Now().Add(24 * time.Hour)
+	})
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{chain.cert}})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("FetchChain", func(t *testing.T) {
+		certs, err := FetchChain("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if !certs[0].NotYetValid || !certs[0].NotBefore.Equal(chain.leaf.NotBefore) {
+			t.Errorf("Unexpected status for a leaf that is not valid yet - %+v", certs[0])
+		}
+		if certs[1].NotYetValid {
+			t.Errorf("Unexpected status for a CA that is valid - %+v", certs[1])
+		}
+	})
+
+	t.Run("NotYetValid", func(t *testing.T) {
+		v, err := NotYetValid("127.0.0.1:9000")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling NotYetValid - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing NotYetValid with a cert valid from tomorrow, expected true got %+v", v)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := NotYetValid("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test selecting the soonest expiring certificate from a chain
+func TestSoonestExpiry(t *testing.T) {
+	now := time.Now()
+	leaf := &CertificateStatus{ExpirationDate: now.Add(48 * time.Hour)}
+	intermediate := &CertificateStatus{ExpirationDate: now.Add(24 * time.Hour)}
+	root := &CertificateStatus{ExpirationDate: now.Add(24 * time.Hour)}
+
+	if v := SoonestExpiry([]*CertificateStatus{leaf, intermediate, root}); v != intermediate {
+		t.Errorf("Unexpected certificate returned, expected the first of the earliest expiring got %+v", v)
+	}
+	if v := SoonestExpiry(nil); v != nil {
+		t.Errorf("Unexpected certificate returned from an empty chain got %+v", v)
+	}
+}
+
+// Test selecting the certificates expiring within a window from a chain
+func TestExpiringBetween(t *testing.T) {
+	now := time.Now()
+	start := &CertificateStatus{ExpirationDate: now.Add(24 * time.Hour)}
+	inside := &CertificateStatus{ExpirationDate: now.Add(36 * time.Hour)}
+	end := &CertificateStatus{ExpirationDate: now.Add(48 * time.Hour)}
+	expired := &CertificateStatus{ExpirationDate: now.Add(-24 * time.Hour)}
+
+	v := expiringBetween([]*CertificateStatus{expired, start, inside, end}, now.Add(24*time.Hour), now.Add(48*time.Hour))
+	if len(v) != 2 || v[0] != start || v[1] != inside {
+		t.Errorf("Unexpected certificates returned, expected the start and inside certs got %+v", v)
+	}
+}
+
+// Test selecting only the expiring certificates from a chain
+func TestExpiringBefore(t *testing.T) {
+	now := time.Now()
+	expired := &CertificateStatus{ExpiredNow: true, ExpirationDate: now.Add(-24 * time.Hour)}
+	expiring := &CertificateStatus{ExpirationDate: now.Add(24 * time.Hour)}
+	valid := &CertificateStatus{ExpirationDate: now.Add(900 * time.Hour)}
+
+	v := expiringBefore([]*CertificateStatus{expired, valid, expiring}, now.Add(48*time.Hour))
+	if len(v) != 2 || v[0] != expired || v[1] != expiring {
+		t.Errorf("Unexpected certificates returned, expected the expired and expiring certs got %+v", v)
+	}
+	if v := expiringBefore([]*CertificateStatus{valid}, now.Add(48*time.Hour)); v == nil || len(v) != 0 {
+		t.Errorf("Unexpected certificates returned, expected an empty slice got %+v", v)
+	}
+}
+
+// Test counting days until expiry for future and past expiration dates
+func TestDaysUntil(t *testing.T) {
+	now := time.Now()
+	tt := []struct {
+		name     string
+		notAfter time.Time
+		days     int
+	}{
+		{"ExpiresInWeeks", now.Add(900 * time.Hour), 37},
+		{"ExpiresLaterToday", now.Add(time.Hour), 0},
+		{"ExpiredToday", now.Add(-1 * time.Minute), -1},
+		{"ExpiredExactlyOneDayAgo", now.Add(-24 * time.Hour), -1},
+		{"ExpiredWeeksAgo", now.Add(-14*24*time.Hour + time.Hour), -14},
+		{"ExpiredOverWeeksAgo", now.Add(-14*24*time.Hour - time.Hour), -15},
+	}
+
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			if v := daysUntil(c.notAfter, now); v != c.days {
+				t.Errorf("Unexpected days until expiry, expected %d got %d", c.days, v)
+			}
+		})
+	}
+}
+
+// Test exclusive and inclusive comparisons at the day boundary
+func TestWithinDays(t *testing.T) {
+	cert := &CertificateStatus{ExpiresInDays: 30}
+	tt := []struct {
+		name      string
+		days      int
+		inclusive bool
+		within    bool
+	}{
+		{"ExclusiveBelow", 29, false, false},
+		{"ExclusiveBoundary", 30, false, false},
+		{"ExclusiveAbove", 31, false, true},
+		{"InclusiveBelow", 29, true, false},
+		{"InclusiveBoundary", 30, true, true},
+		{"InclusiveAbove", 31, true, true},
+	}
+
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			if v := withinDays(cert, c.days, c.inclusive); v != c.within {
+				t.Errorf("Unexpected result for a cert with 30 days remaining within %d days, expected %t got %t", c.days, c.within, v)
+			}
+		})
+	}
+}
+
+// Test evaluating expiry against an injected clock
+func TestClock(t *testing.T) {
+	expires := time.Now().Add(900 * time.Hour)
+	chain, err := genChain(expires, nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf}}}, nil
+	})
+	clock := func(t time.Time) Option {
+		return WithClock(func() time.Time { return t })
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		check, err := Expired("example.com", dialer)
+		if err != nil || check {
+			t.Errorf("Unexpected expired result with the default clock got %t, %v", check, err)
+		}
+	})
+
+	t.Run("Future", func(t *testing.T) {
+		certs, err := FetchChain("example.com", dialer, clock(chain.leaf.NotAfter.Add(47*time.Hour)))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if !certs[0].ExpiredNow || certs[0].ExpiresInDays != -2 {
+			t.Errorf("Unexpected status with a future clock got expired=%t days=%d", certs[0].ExpiredNow, certs[0].ExpiresInDays)
+		}
+		check, err := Expired("example.com", dialer, clock(expires.Add(48*time.Hour)))
+		if err != nil || !check {
+			t.Errorf("Expected expired result with a future clock got %t, %v", check, err)
+		}
+	})
+
+	t.Run("Past", func(t *testing.T) {
+		certs, err := FetchChain("example.com", dialer, clock(chain.leaf.NotBefore.Add(-time.Hour)))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if !certs[0].NotYetValid {
+			t.Errorf("Expected certificate to not be valid yet with a past clock")
+		}
+	})
+
+	t.Run("ExpiresWithin", func(t *testing.T) {
+		check, err := ExpiresWithin("example.com", 48*time.Hour, dialer, clock(expires.Add(-24*time.Hour)))
+		if err != nil || !check {
+			t.Errorf("Expected certificate to expire within 48 hours of the clock got %t, %v", check, err)
+		}
+		certs, err := ExpiringCerts("example.com", 48*time.Hour, dialer, clock(expires.Add(-72*time.Hour)))
+		if err != nil || len(certs) != 0 {
+			t.Errorf("Expected no certificates expiring within 48 hours of the clock got %d, %v", len(certs), err)
+		}
+	})
+}
+
+// Test the exact duration until the soonest expiring certificate
+func TestTimeUntilExpiry(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	expiring, err := genChain(time.Now().Add(30*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, expiring.ca, chain.ca}}}, nil
+	})
+	soonest := chain.leaf.NotAfter
+	if expiring.ca.NotAfter.Before(soonest) {
+		soonest = expiring.ca.NotAfter
+	}
+
+	tt := []struct {
+		name     string
+		now      time.Time
+		expected time.Duration
+	}{
+		{"Remaining", soonest.Add(-90 * time.Minute), 90 * time.Minute},
+		{"ExpiresNow", soonest, 0},
+		{"Expired", soonest.Add(2 * time.Hour), -2 * time.Hour},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := TimeUntilExpiry("example.com", dialer, WithClock(func() time.Time { return c.now }))
+			if err != nil {
+				t.Fatalf("Unexpected failure when calling TimeUntilExpiry - %s", err)
+			}
+			if d != c.expected {
+				t.Errorf("Unexpected duration until expiry, expected %s got %s", c.expected, d)
+			}
+		})
+	}
+
+	t.Run("FetchError", func(t *testing.T) {
+		d, err := TimeUntilExpiry("127.0.0.1:1")
+		if err == nil || d != 0 {
+			t.Errorf("Expected TimeUntilExpiry to fail with an unreachable address, got %s %v", d, err)
+		}
+	})
 }
@@ -0,0 +1,108 @@
+// Package hazexpiredprom provides a Prometheus collector that reports the certificate expiry of remote systems,
+// keeping the Prometheus dependency out of the core hazexpired package.
+//
+//	c := hazexpiredprom.NewCollector([]string{"example.com:443"})
+//	prometheus.MustRegister(c)
+package hazexpiredprom
+
+import (
+	"context"
+	"time"
+
+	"github.com/madflojo/hazexpired"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector which fetches the certificate chain of each configured address on every scrape.
+type Collector struct {
+	// addresses are the remote systems checked on each scrape
+	addresses []string
+
+	// opts are the hazexpired options used when fetching each certificate chain
+	opts []hazexpired.Option
+
+	// success describes whether the certificate chain for an address could be fetched
+	success *prometheus.Desc
+
+	// expiry describes the seconds until a certificate expires
+	expiry *prometheus.Desc
+
+	// expired describes whether a certificate is currently expired
+	expired *prometheus.Desc
+}
+
+// NewCollector will create a Collector for the provided addresses, the options are used for each fetch. Addresses
+// are fetched concurrently on each scrape, this can be tuned with hazexpired.WithConcurrency.
+func NewCollector(addresses []string, opts ...hazexpired.Option) *Collector {
+	certLabels := []string{"address", "subject", "serial_number"}
+	return &Collector{
+		addresses: addresses,
+		opts:      opts,
+		success: prometheus.NewDesc(
+			"hazexpired_probe_success",
+			"Whether the certificate chain for the address was fetched successfully.",
+			[]string{"address"}, nil,
+		),
+		expiry: prometheus.NewDesc(
+			"hazexpired_certificate_expiry_seconds",
+			"Number of seconds until the certificate expires, negative when already expired.",
+			certLabels, nil,
+		),
+		expired: prometheus.NewDesc(
+			"hazexpired_certificate_expired",
+			"Whether the certificate is currently expired.",
+			certLabels, nil,
+		),
+	}
+}
+
+// Describe sends the descriptors of each metric reported by the Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.success
+	ch <- c.expiry
+	ch <- c.expired
+}
+
+// Collect will fetch the certificate chain for each address and send the resulting metrics. Certificates presented
+// more than once by an address are reported once. Expiry seconds are measured from the real time, regardless of
+// hazexpired.WithClock.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.CollectContext(context.Background(), ch)
+}
+
+// CollectContext is the same as Collect but uses the provided context to cancel or set a deadline on fetching the certificate chains.
+func (c *Collector) CollectContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	results, err := hazexpired.ExpiredBatchContext(ctx, c.addresses, c.opts...)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.success, err)
+		return
+	}
+
+	now := time.Now()
+	for address, r := range results {
+		success := 1.0
+		if r.Err != nil {
+			success = 0
+		}
+		ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, success, address)
+
+		// Remote systems may present the same certificate more than once, which would otherwise report duplicate series
+		seen := make(map[string]bool, len(r.Chain))
+		for _, cert := range r.Chain {
+			if seen[cert.Fingerprint] {
+				continue
+			}
+			seen[cert.Fingerprint] = true
+			serial := ""
+			if cert.SerialNumber != nil {
+				serial = cert.SerialNumber.String()
+			}
+			expired := 0.0
+			if cert.ExpiredNow {
+				expired = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.expiry, prometheus.GaugeValue, cert.ExpirationDate.Sub(now).Seconds(), address, cert.Subject, serial)
+			ch <- prometheus.MustNewConstMetric(c.expired, prometheus.GaugeValue, expired, address, cert.Subject, serial)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package hazexpiredprom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// Test collecting metrics for reachable and unreachable addresses
+func TestCollector(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	c := NewCollector([]string{srv.Listener.Addr().String(), "iamateapot:418"})
+
+	if n := testutil.CollectAndCount(c, "hazexpired_probe_success"); n != 2 {
+		t.Errorf("Unexpected number of probe success metrics, expected 2 got %d", n)
+	}
+	if n := testutil.CollectAndCount(c, "hazexpired_certificate_expiry_seconds"); n != 1 {
+		t.Errorf("Unexpected number of certificate expiry metrics, expected 1 got %d", n)
+	}
+	if n := testutil.CollectAndCount(c, "hazexpired_certificate_expired"); n != 1 {
+		t.Errorf("Unexpected number of certificate expired metrics, expected 1 got %d", n)
+	}
+}
+
+// Test that a certificate presented more than once by an address is reported once
+func TestCollectorDuplicateCertificates(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	cert := &srv.TLS.Certificates[0]
+	cert.Certificate = append(cert.Certificate, cert.Certificate[0])
+
+	c := NewCollector([]string{srv.Listener.Addr().String()})
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Unable to register collector - %s", err)
+	}
+	if _, err := reg.Gather(); err != nil {
+		t.Errorf("Unexpected failure when gathering duplicate certificates - %s", err)
+	}
+	if n := testutil.CollectAndCount(c, "hazexpired_certificate_expiry_seconds"); n != 1 {
+		t.Errorf("Unexpected number of certificate expiry metrics, expected 1 got %d", n)
+	}
+}
@@ -0,0 +1,125 @@
+// Package hazexpiredtest provides a throwaway TLS server presenting a certificate with a chosen expiration date, for
+// integration testing code built on the hazexpired package against controlled certificates.
+//
+//	address, cleanup := hazexpiredtest.NewTestServer(time.Now().Add(24 * time.Hour))
+//	defer cleanup()
+//	check, err := hazexpired.ExpiresWithinDays(address, 30)
+package hazexpiredtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// connTimeout bounds how long the server waits on each connection, so clients which never complete a handshake do
+// not tie up the server.
+const connTimeout = 5 * time.Second
+
+// NewTestServer will start a TLS server on a random loopback port presenting a self-signed certificate for localhost
+// and 127.0.0.1 which expires at notAfter. The certificate is valid from an hour ago, or from a year before notAfter
+// when notAfter is already past. The returned address is in host:port form, and the cleanup function stops the
+// server and closes any open connections. Like httptest.NewServer, it panics if the server cannot be started.
+func NewTestServer(notAfter time.Time) (string, func()) {
+	cert, err := NewCertificate(notAfter)
+	if err != nil {
+		panic(fmt.Sprintf("hazexpiredtest: %s", err))
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		panic(fmt.Sprintf("hazexpiredtest: Could not start test server - %s", err))
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns[conn] = struct{}{}
+			mu.Unlock()
+
+			// Read from each connection until the client closes it or the timeout passes, tls handshakes occur on
+			// first read attempt
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					mu.Lock()
+					delete(conns, conn)
+					mu.Unlock()
+					conn.Close()
+				}()
+				_ = conn.SetDeadline(time.Now().Add(connTimeout))
+				b := make([]byte, 2)
+				_, _ = conn.Read(b)
+			}()
+		}
+	}()
+
+	return l.Addr().String(), func() {
+		l.Close()
+		mu.Lock()
+		for conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}
+}
+
+// NewCertificate will create a self-signed certificate and key for localhost and 127.0.0.1 which expires at notAfter,
+// for use with a custom test server. The validity period begins as described by NewTestServer.
+func NewCertificate(notAfter time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Could not generate ecdsa key - %s", err)
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	if !notBefore.Before(notAfter) {
+		notBefore = notAfter.Add(-8760 * time.Hour)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Could not generate serial number - %s", err)
+	}
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{"I Can Haz Expired Certs"},
+			CommonName:   "localhost",
+		},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		SerialNumber:          serial,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Could not generate certificate - %s", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Could not parse certificate - %s", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
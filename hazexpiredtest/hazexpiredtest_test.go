@@ -0,0 +1,59 @@
+package hazexpiredtest
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+// Test starting a server presenting a certificate with the requested expiration date
+func TestNewTestServer(t *testing.T) {
+	tt := map[string]time.Time{
+		"Valid":   time.Now().Add(900 * time.Hour).Truncate(time.Second),
+		"Expired": time.Now().Add(-900 * time.Hour).Truncate(time.Second),
+	}
+
+	for name, notAfter := range tt {
+		t.Run(name, func(t *testing.T) {
+			address, cleanup := NewTestServer(notAfter)
+			defer cleanup()
+
+			conn, err := tls.Dial("tcp", address, &tls.Config{InsecureSkipVerify: true})
+			if err != nil {
+				t.Fatalf("Unexpected failure connecting to test server - %s", err)
+			}
+			defer conn.Close()
+			certs := conn.ConnectionState().PeerCertificates
+			if len(certs) != 1 {
+				t.Fatalf("Unexpected number of certificates from test server got %d", len(certs))
+			}
+			if !certs[0].NotAfter.Equal(notAfter) {
+				t.Errorf("Unexpected expiration date, expected %s got %s", notAfter, certs[0].NotAfter)
+			}
+			if !certs[0].NotBefore.Before(notAfter) {
+				t.Errorf("Unexpected validity period, %s is not before %s", certs[0].NotBefore, notAfter)
+			}
+		})
+	}
+
+	t.Run("Cleanup", func(t *testing.T) {
+		address, cleanup := NewTestServer(time.Now().Add(time.Hour))
+
+		// An idle connection should be closed by cleanup rather than waiting for the connection timeout
+		start := time.Now()
+		idle, err := tls.Dial("tcp", address, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("Unexpected failure connecting to test server - %s", err)
+		}
+		defer idle.Close()
+		cleanup()
+		if time.Since(start) >= connTimeout {
+			t.Errorf("Unexpected wait for idle connection during cleanup took %s", time.Since(start))
+		}
+
+		_, err = tls.Dial("tcp", address, &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			t.Errorf("Expected failure connecting to test server after cleanup, err is nil")
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Healthy will return true only if the remote system's leaf certificate is usable right now, giving a single signal
+// for health checks and dashboards. It returns false when any of the following hold:
+//   - The leaf certificate has expired.
+//   - The leaf certificate is not yet valid.
+//   - The leaf certificate does not cover the hostname, which is the one set by WithServerName or otherwise the host
+//     portion of the address, matched using the rules documented on MatchesHostname.
+//
+// Intermediate and root certificates, and whether the chain is trusted, are not considered. An error is returned,
+// along with false, when the certificate chain could not be fetched or the hostname is not a valid internationalized
+// hostname.
+func Healthy(address string, opts ...Option) (bool, error) {
+	return HealthyContext(context.Background(), address, opts...)
+}
+
+// HealthyContext is the same as Healthy but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func HealthyContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	leaf, err := fetchLeaf(ctx, address, opts...)
+	if err != nil {
+		return false, err
+	}
+	if leaf.ExpiredNow || leaf.NotYetValid {
+		return false, nil
+	}
+	host, err := toASCII(newConfig(opts...).hostname(address))
+	if err != nil {
+		return false, err
+	}
+	return matchHostname(leaf.Certificate, host), nil
+}
+
+// ValidAt will return true if the remote system's leaf certificate is valid at the time t, which is useful to confirm a
+// certificate will still be valid during a future maintenance window. The leaf is valid when t falls between its
+// NotBefore and NotAfter dates inclusive. When it is not, false is returned with an error wrapping ErrNotYetValid or
+// ErrExpired describing which condition failed. An error is also returned when the certificate chain could not be
+// fetched.
+func ValidAt(address string, t time.Time, opts ...Option) (bool, error) {
+	return ValidAtContext(context.Background(), address, t, opts...)
+}
+
+// ValidAtContext is the same as ValidAt but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ValidAtContext(ctx context.Context, address string, t time.Time, opts ...Option) (bool, error) {
+	leaf, err := fetchLeaf(ctx, address, opts...)
+	if err != nil {
+		return false, err
+	}
+	if t.Before(leaf.Certificate.NotBefore) {
+		return false, fmt.Errorf("%w at %s, valid from %s", ErrNotYetValid, t.Format(time.RFC3339), leaf.Certificate.NotBefore.Format(time.RFC3339))
+	}
+	if t.After(leaf.Certificate.NotAfter) {
+		return false, fmt.Errorf("%w at %s, expired %s", ErrExpired, t.Format(time.RFC3339), leaf.Certificate.NotAfter.Format(time.RFC3339))
+	}
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test the single signal health check of the leaf certificate
+func TestHealthy(t *testing.T) {
+	valid, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	expired, err := genChain(time.Now().Add(-time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	future, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.NotBefore = time.Now().Add(time.Hour)
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := func(certs ...*x509.Certificate) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: certs}}, nil
+		})
+	}
+
+	tt := []struct {
+		name    string
+		address string
+		opts    []Option
+		healthy bool
+	}{
+		{"Healthy", "localhost:443", []Option{dialer(valid.leaf, valid.ca)}, true},
+		{"Expired", "localhost:443", []Option{dialer(expired.leaf, expired.ca)}, false},
+		{"NotYetValid", "localhost:443", []Option{dialer(future.leaf, future.ca)}, false},
+		{"HostnameMismatch", "example.com:443", []Option{dialer(valid.leaf, valid.ca)}, false},
+		{"ServerName", "127.0.0.1:443", []Option{dialer(valid.leaf, valid.ca), WithServerName("localhost")}, true},
+		// an expired CA does not affect the leaf
+		{"ExpiredCA", "localhost:443", []Option{dialer(valid.leaf, expired.ca)}, true},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			healthy, err := Healthy(c.address, c.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected failure when calling Healthy - %s", err)
+			}
+			if healthy != c.healthy {
+				t.Errorf("Unexpected result from Healthy, expected %t got %t", c.healthy, healthy)
+			}
+		})
+	}
+
+	t.Run("EmptyChain", func(t *testing.T) {
+		healthy, err := Healthy("localhost:443", dialer())
+		if !errors.Is(err, ErrNoCertificates) || healthy {
+			t.Errorf("Expected unhealthy result and ErrNoCertificates from an empty chain, got %t and %v", healthy, err)
+		}
+	})
+
+	t.Run("InvalidHostname", func(t *testing.T) {
+		// a long label ending in a high code point overflows punycode encoding
+		host := strings.Repeat("a", 2000) + "\U0010FFFF"
+		healthy, err := Healthy("localhost:443", dialer(valid.leaf, valid.ca), WithServerName(host))
+		if err == nil || healthy {
+			t.Errorf("Expected unhealthy result and an error from an invalid hostname, got %t and %v", healthy, err)
+		}
+	})
+}
+
+// Test checking the leaf certificate is valid at a future time
+func TestValidAt(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+
+	tt := []struct {
+		name  string
+		at    time.Time
+		valid bool
+		err   error
+	}{
+		{"Valid", time.Now().Add(800 * time.Hour), true, nil},
+		{"NotAfter", chain.leaf.NotAfter, true, nil},
+		{"NotBefore", chain.leaf.NotBefore, true, nil},
+		{"Expired", chain.leaf.NotAfter.Add(time.Second), false, ErrExpired},
+		{"NotYetValid", chain.leaf.NotBefore.Add(-time.Second), false, ErrNotYetValid},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			valid, err := ValidAt("localhost:443", c.at, dialer)
+			if !errors.Is(err, c.err) {
+				t.Errorf("Unexpected error from ValidAt, expected %v got %v", c.err, err)
+			}
+			if valid != c.valid {
+				t.Errorf("Unexpected result from ValidAt, expected %t got %t", c.valid, valid)
+			}
+		})
+	}
+
+	t.Run("FetchError", func(t *testing.T) {
+		valid, err := ValidAt("127.0.0.1:1", time.Now())
+		if err == nil || valid {
+			t.Errorf("Expected ValidAt to fail with an unreachable address, got %t %v", valid, err)
+		}
+	})
+}
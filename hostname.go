@@ -0,0 +1,198 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+)
+
+// MatchesHostname will return true if the remote system's leaf certificate covers the provided hostname, independent
+// of whether the chain is trusted. The hostname is also sent as the SNI server name unless overridden with
+// WithServerName.
+//
+// Matching follows the common rules used by browsers:
+//   - Hostnames are compared case-insensitively and a trailing dot is ignored.
+//   - Internationalized hostnames are converted to their punycode (xn--) form before comparison, without any further
+//     IDNA normalization.
+//   - The leaf's DNS Subject Alternative Names are used, falling back to the Subject Common Name only when the
+//     certificate has no DNS names.
+//   - A wildcard is only honored as the entire left-most label, such as *.example.com, and matches exactly one
+//     label, so it covers www.example.com but not example.com or a.b.example.com.
+//   - IP address hostnames are only matched against the certificate's IP Subject Alternative Names.
+func MatchesHostname(address, hostname string, opts ...Option) (bool, error) {
+	return MatchesHostnameContext(context.Background(), address, hostname, opts...)
+}
+
+// MatchesHostnameContext is the same as MatchesHostname but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func MatchesHostnameContext(ctx context.Context, address, hostname string, opts ...Option) (bool, error) {
+	host, err := toASCII(hostname)
+	if err != nil {
+		return false, err
+	}
+	if net.ParseIP(host) == nil {
+		opts = append([]Option{WithServerName(host)}, opts...)
+	}
+	leaf, err := fetchLeaf(ctx, address, opts...)
+	if err != nil {
+		return false, err
+	}
+	return matchHostname(leaf.Certificate, host), nil
+}
+
+// matchHostname will return true if the certificate covers the ASCII hostname using the rules documented on MatchesHostname.
+func matchHostname(cert *x509.Certificate, hostname string) bool {
+	if ip := net.ParseIP(hostname); ip != nil {
+		for _, certIP := range cert.IPAddresses {
+			if certIP.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	names := cert.DNSNames
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = []string{cert.Subject.CommonName}
+	}
+	for _, name := range names {
+		name, err := toASCII(name)
+		if err != nil {
+			continue
+		}
+		if name == hostname {
+			return true
+		}
+		// Wildcards only cover a single, non-empty, left-most label
+		if base, ok := strings.CutPrefix(name, "*."); ok {
+			label, rest, found := strings.Cut(hostname, ".")
+			if found && label != "" && rest == base {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toASCII will lowercase a hostname, remove any trailing dot, and convert each internationalized label to its
+// punycode form.
+func toASCII(hostname string) (string, error) {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(hostname), "."), ".")
+	for i, label := range labels {
+		ascii := true
+		for _, r := range label {
+			if r >= 0x80 {
+				ascii = false
+				break
+			}
+		}
+		if ascii {
+			continue
+		}
+		encoded, err := punycode(label)
+		if err != nil {
+			return "", fmt.Errorf("Invalid hostname %s - %s", hostname, err)
+		}
+		labels[i] = "xn--" + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// Punycode parameters as defined by RFC 3492.
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// punycode will encode a label using the Punycode algorithm from RFC 3492.
+func punycode(label string) (string, error) {
+	runes := []rune(label)
+	var out []byte
+	for _, r := range runes {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		}
+	}
+	basic := len(out)
+	handled := basic
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := punyInitialN, 0, punyInitialBias
+	for handled < len(runes) {
+		// Find the smallest code point not yet handled
+		m := math.MaxInt32
+		for _, r := range runes {
+			if int(r) >= n && int(r) < m {
+				m = int(r)
+			}
+		}
+		if (m - n) > (math.MaxInt32-delta)/(handled+1) {
+			return "", fmt.Errorf("punycode overflow")
+		}
+		delta += (m - n) * (handled + 1)
+		n = m
+
+		for _, r := range runes {
+			if int(r) < n {
+				delta++
+			}
+			if int(r) != n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := k - bias
+				if t < punyTMin {
+					t = punyTMin
+				} else if t > punyTMax {
+					t = punyTMax
+				}
+				if q < t {
+					break
+				}
+				out = append(out, punyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			out = append(out, punyDigit(q))
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out), nil
+}
+
+// punyAdapt is the Punycode bias adaptation function from RFC 3492.
+func punyAdapt(delta, points int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+// punyDigit will return the Punycode character representing the digit.
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
@@ -0,0 +1,115 @@
+package hazexpired
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test converting internationalized hostnames to punycode
+func TestToASCII(t *testing.T) {
+	tt := []struct {
+		hostname string
+		ascii    string
+	}{
+		{"Example.COM.", "example.com"},
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"MÜNCHEN.de", "xn--mnchen-3ya.de"},
+		{"例え.jp", "xn--r8jz45g.jp"},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example"},
+	}
+
+	for _, c := range tt {
+		t.Run(c.hostname, func(t *testing.T) {
+			v, err := toASCII(c.hostname)
+			if err != nil {
+				t.Fatalf("Unexpected failure when converting %s - %s", c.hostname, err)
+			}
+			if v != c.ascii {
+				t.Errorf("Unexpected ASCII hostname from %s, expected %s got %s", c.hostname, c.ascii, v)
+			}
+		})
+	}
+}
+
+// Test hostname matching rules against a certificate
+func TestMatchHostname(t *testing.T) {
+	cert := &x509.Certificate{
+		DNSNames:    []string{"example.com", "*.example.org", "xn--bcher-kva.example"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.1")},
+	}
+	cnOnly := &x509.Certificate{Subject: pkix.Name{CommonName: "legacy.example.com"}}
+
+	tt := []struct {
+		name     string
+		cert     *x509.Certificate
+		hostname string
+		match    bool
+	}{
+		{"Exact", cert, "example.com", true},
+		{"Subdomain", cert, "www.example.com", false},
+		{"Wildcard", cert, "www.example.org", true},
+		{"WildcardBase", cert, "example.org", false},
+		{"WildcardMultipleLabels", cert, "a.b.example.org", false},
+		{"Punycode", cert, "xn--bcher-kva.example", true},
+		{"IPAddress", cert, "192.0.2.1", true},
+		{"WrongIPAddress", cert, "192.0.2.2", false},
+		{"CommonNameFallback", cnOnly, "legacy.example.com", true},
+	}
+
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			if v := matchHostname(c.cert, c.hostname); v != c.match {
+				t.Errorf("Unexpected match result for %s, expected %t got %t", c.hostname, c.match, v)
+			}
+		})
+	}
+}
+
+// Test matching the remote system's leaf certificate against a hostname
+func TestMatchesHostname(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Match", func(t *testing.T) {
+		v, err := MatchesHostname("127.0.0.1:9000", "LOCALHOST")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling MatchesHostname - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when matching localhost, expected true got %t", v)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		v, err := MatchesHostname("127.0.0.1:9000", "example.com")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling MatchesHostname - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when matching example.com, expected false got %t", v)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := MatchesHostname("iamateapot:418", "localhost")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// issuerCacheTTL is how long issuer certificates downloaded with WithFetchIssuers are cached.
+	issuerCacheTTL = 24 * time.Hour
+
+	// maxIssuerFetches limits how many issuers are followed beyond the certificates the remote system presents.
+	maxIssuerFetches = 4
+
+	// maxIssuerSize limits the size of issuer certificates downloaded from a CA Issuers URL.
+	maxIssuerSize = 1 << 20
+)
+
+// issuerCache holds downloaded issuer certificates keyed by CA Issuers URL, shared across calls to avoid refetching.
+var issuerCache = struct {
+	sync.Mutex
+	entries map[string]issuerCacheEntry
+}{entries: make(map[string]issuerCacheEntry)}
+
+// issuerCacheEntry is a cached issuer certificate and the time it should no longer be used.
+type issuerCacheEntry struct {
+	cert    *x509.Certificate
+	expires time.Time
+}
+
+// fetchMissingIssuers will follow the Authority Information Access CA Issuers URL of the last certificate presented
+// by the remote system, downloading each issuer the remote system did not send until a self-signed certificate is
+// reached. Each issuer is returned with its Source set to the URL it was downloaded from. Following stops quietly at
+// the first issuer which cannot be downloaded, parsed, or did not sign the certificate before it.
+func (cfg *config) fetchMissingIssuers(ctx context.Context, certs []*x509.Certificate) []*CertificateStatus {
+	if len(certs) == 0 {
+		return nil
+	}
+	var chain []*CertificateStatus
+	now := cfg.now()
+	last := certs[len(certs)-1]
+	for i := 0; i < maxIssuerFetches; i++ {
+		if selfSigned(last) || len(last.IssuingCertificateURL) == 0 {
+			break
+		}
+		url := last.IssuingCertificateURL[0]
+		issuer, err := cfg.fetchIssuer(ctx, url)
+		if err != nil || last.CheckSignatureFrom(issuer) != nil {
+			break
+		}
+		status := newCertificateStatus(issuer, now)
+		status.Role = certificateRole(issuer, len(certs)+i)
+		status.Source = url
+		status.VerifyError = verifyChain([]*x509.Certificate{issuer}, "", cfg.rootCAs, now)
+		chain = append(chain, status)
+		last = issuer
+	}
+	return chain
+}
+
+// fetchIssuer will return the issuer certificate from the CA Issuers URL, using the cache when a valid entry exists.
+// Expired cache entries are evicted on each lookup.
+func (cfg *config) fetchIssuer(ctx context.Context, url string) (*x509.Certificate, error) {
+	now := time.Now()
+	issuerCache.Lock()
+	for key, entry := range issuerCache.entries {
+		if !now.Before(entry.expires) {
+			delete(issuerCache.entries, key)
+		}
+	}
+	entry, ok := issuerCache.entries[url]
+	issuerCache.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.cert, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create issuer request for %s - %s", url, err)
+	}
+	resp, err := cfg.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Could not download issuer from %s - %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CA Issuers URL %s returned %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIssuerSize))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read issuer from %s - %s", url, err)
+	}
+
+	// Issuers are normally DER encoded but some CAs serve PEM
+	if block, _ := pem.Decode(data); block != nil && block.Type == "CERTIFICATE" {
+		data = block.Bytes
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse issuer from %s - %s", url, err)
+	}
+
+	issuerCache.Lock()
+	issuerCache.entries[url] = issuerCacheEntry{cert: cert, expires: now.Add(issuerCacheTTL)}
+	issuerCache.Unlock()
+	return cert, nil
+}
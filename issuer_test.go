@@ -0,0 +1,165 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test following the CA Issuers URL to fetch issuers the remote system omits
+func TestFetchIssuers(t *testing.T) {
+	var downloads int32
+	var issuer []byte
+	caIssuers := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		if r.URL.Path == "/missing.crt" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(issuer)
+	}))
+	defer caIssuers.Close()
+
+	dialer := func(leaf *x509.Certificate) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}}, nil
+		})
+	}
+	leafFor := func(path string) *testChain {
+		chain, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+			leaf.IssuingCertificateURL = []string{caIssuers.URL + path}
+		})
+		if err != nil {
+			t.Fatalf("Unable to generate test certificates - %s", err)
+		}
+		return chain
+	}
+
+	t.Run("DER", func(t *testing.T) {
+		chain := leafFor("/der.crt")
+		issuer = chain.ca.Raw
+		certs, err := FetchChain("example.com", dialer(chain.leaf), WithFetchIssuers())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if len(certs) != 2 || !certs[1].Certificate.Equal(chain.ca) {
+			t.Fatalf("Unexpected Certificate Chain, expected the fetched issuer got %+v", certs)
+		}
+		if certs[1].Source != caIssuers.URL+"/der.crt" || certs[1].Role != RoleRoot {
+			t.Errorf("Unexpected fetched issuer, expected source %s and root role got %s %s", caIssuers.URL+"/der.crt", certs[1].Source, certs[1].Role)
+		}
+		if certs[0].Source != "example.com" {
+			t.Errorf("Unexpected leaf source got %s", certs[0].Source)
+		}
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		chain := leafFor("/cached.crt")
+		issuer = chain.ca.Raw
+		before := atomic.LoadInt32(&downloads)
+		for i := 0; i < 2; i++ {
+			certs, err := FetchChain("example.com", dialer(chain.leaf), WithFetchIssuers())
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+			}
+			if len(certs) != 2 {
+				t.Fatalf("Unexpected Certificate Chain length got %d", len(certs))
+			}
+		}
+		if v := atomic.LoadInt32(&downloads) - before; v != 1 {
+			t.Errorf("Unexpected issuer downloads, expected cached issuer to be used got %d downloads", v)
+		}
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		issuerCache.Lock()
+		issuerCache.entries["http://stale.example.com/stale.crt"] = issuerCacheEntry{expires: time.Now().Add(-time.Minute)}
+		issuerCache.Unlock()
+		chain := leafFor("/evict.crt")
+		issuer = chain.ca.Raw
+		_, err := FetchChain("example.com", dialer(chain.leaf), WithFetchIssuers())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		issuerCache.Lock()
+		defer issuerCache.Unlock()
+		if _, ok := issuerCache.entries["http://stale.example.com/stale.crt"]; ok {
+			t.Errorf("Expected the stale issuer cache entry to be evicted")
+		}
+	})
+
+	t.Run("PEM", func(t *testing.T) {
+		chain := leafFor("/pem.crt")
+		issuer = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: chain.ca.Raw})
+		certs, err := FetchChain("example.com", dialer(chain.leaf), WithFetchIssuers())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if len(certs) != 2 || !certs[1].Certificate.Equal(chain.ca) {
+			t.Errorf("Unexpected Certificate Chain, expected the fetched issuer got %+v", certs)
+		}
+	})
+
+	t.Run("WrongIssuer", func(t *testing.T) {
+		chain := leafFor("/wrong.crt")
+		other, err := genChain(time.Now().Add(900*time.Hour), nil)
+		if err != nil {
+			t.Fatalf("Unable to generate test certificates - %s", err)
+		}
+		issuer = other.ca.Raw
+		certs, err := FetchChain("example.com", dialer(chain.leaf), WithFetchIssuers())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if len(certs) != 1 {
+			t.Errorf("Unexpected Certificate Chain, expected an issuer which did not sign the leaf to be ignored got %+v", certs)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		chain := leafFor("/missing.crt")
+		certs, err := FetchChain("example.com", dialer(chain.leaf), WithFetchIssuers())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if len(certs) != 1 {
+			t.Errorf("Unexpected Certificate Chain, expected only the presented leaf got %+v", certs)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		chain := leafFor("/disabled.crt")
+		issuer = chain.ca.Raw
+		certs, err := FetchChain("example.com", dialer(chain.leaf))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if len(certs) != 1 {
+			t.Errorf("Unexpected Certificate Chain, expected issuers not to be fetched by default got %+v", certs)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		chain := leafFor("/stream.crt")
+		issuer = chain.ca.Raw
+		var count int
+		for status, err := range StreamChain("example.com", dialer(chain.leaf), WithFetchIssuers()) {
+			if err != nil {
+				t.Fatalf("Unexpected failure when streaming Certificate Chain - %s", err)
+			}
+			if count == 1 && !status.Certificate.Equal(chain.ca) {
+				t.Errorf("Unexpected streamed issuer got %+v", status)
+			}
+			count++
+		}
+		if count != 2 {
+			t.Errorf("Unexpected streamed Certificate Chain length, expected 2 got %d", count)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package hazexpired
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// certificateStatusJSON is the stable JSON representation of a CertificateStatus.
+type certificateStatusJSON struct {
+	ExpiredNow         bool     `json:"expired_now"`
+	ExpiresInDays      int      `json:"expires_in_days"`
+	ExpirationDate     string   `json:"expiration_date"`
+	NotYetValid        bool     `json:"not_yet_valid"`
+	NotBefore          string   `json:"not_before"`
+	LifetimeRemaining  float64  `json:"lifetime_remaining_percent"`
+	Signature          []byte   `json:"signature"`
+	SerialNumber       string   `json:"serial_number"`
+	Subject            string   `json:"subject"`
+	Issuer             string   `json:"issuer"`
+	DNSNames           []string `json:"dns_names,omitempty"`
+	SignatureAlgorithm string   `json:"signature_algorithm"`
+	KeyBits            int      `json:"key_bits"`
+	Fingerprint        string   `json:"fingerprint"`
+	SelfSigned         bool     `json:"self_signed"`
+	Role               string   `json:"role,omitempty"`
+	VerifyError        string   `json:"verify_error,omitempty"`
+	HostnameValid      bool     `json:"hostname_valid"`
+	PublicCA           bool     `json:"public_ca"`
+	Source             string   `json:"source,omitempty"`
+	Raw                []byte   `json:"raw,omitempty"`
+}
+
+// MarshalJSON will encode the CertificateStatus using stable snake_case field names. The ExpirationDate and NotBefore
+// are rendered in RFC3339 format, the SerialNumber as a decimal string, the Signature as base64, and the
+// SignatureAlgorithm and VerifyError as their string forms. The parsed Certificate is not included, the Raw DER is
+// included as base64 when present.
+func (s CertificateStatus) MarshalJSON() ([]byte, error) {
+	j := certificateStatusJSON{
+		ExpiredNow:         s.ExpiredNow,
+		ExpiresInDays:      s.ExpiresInDays,
+		ExpirationDate:     s.ExpirationDate.Format(time.RFC3339),
+		NotYetValid:        s.NotYetValid,
+		NotBefore:          s.NotBefore.Format(time.RFC3339),
+		LifetimeRemaining:  s.LifetimeRemainingPercent,
+		Signature:          s.Signature,
+		Subject:            s.Subject,
+		Issuer:             s.Issuer,
+		DNSNames:           s.DNSNames,
+		SignatureAlgorithm: s.SignatureAlgorithm.String(),
+		KeyBits:            s.KeyBits,
+		Fingerprint:        s.Fingerprint,
+		SelfSigned:         s.SelfSigned,
+		Role:               string(s.Role),
+		HostnameValid:      s.HostnameValid,
+		PublicCA:           s.PublicCA,
+		Source:             s.Source,
+		Raw:                s.Raw,
+	}
+	if s.SerialNumber != nil {
+		j.SerialNumber = s.SerialNumber.String()
+	}
+	if s.VerifyError != nil {
+		j.VerifyError = s.VerifyError.Error()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON will decode a CertificateStatus previously encoded with MarshalJSON. The VerifyError is restored as
+// a plain error holding the original message. The parsed Certificate is restored from the Raw DER when present,
+// otherwise it is left nil.
+func (s *CertificateStatus) UnmarshalJSON(data []byte) error {
+	var j certificateStatusJSON
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return err
+	}
+
+	*s = CertificateStatus{
+		ExpiredNow:               j.ExpiredNow,
+		ExpiresInDays:            j.ExpiresInDays,
+		NotYetValid:              j.NotYetValid,
+		LifetimeRemainingPercent: j.LifetimeRemaining,
+		Signature:                j.Signature,
+		Subject:                  j.Subject,
+		Issuer:                   j.Issuer,
+		DNSNames:                 j.DNSNames,
+		KeyBits:                  j.KeyBits,
+		Fingerprint:              j.Fingerprint,
+		SelfSigned:               j.SelfSigned,
+		Role:                     Role(j.Role),
+		HostnameValid:            j.HostnameValid,
+		PublicCA:                 j.PublicCA,
+		Source:                   j.Source,
+		Raw:                      j.Raw,
+	}
+	if j.ExpirationDate != "" {
+		s.ExpirationDate, err = time.Parse(time.RFC3339, j.ExpirationDate)
+		if err != nil {
+			return fmt.Errorf("Invalid expiration_date %s - %s", j.ExpirationDate, err)
+		}
+	}
+	if j.NotBefore != "" {
+		s.NotBefore, err = time.Parse(time.RFC3339, j.NotBefore)
+		if err != nil {
+			return fmt.Errorf("Invalid not_before %s - %s", j.NotBefore, err)
+		}
+	}
+	if j.SerialNumber != "" {
+		var ok bool
+		s.SerialNumber, ok = new(big.Int).SetString(j.SerialNumber, 10)
+		if !ok {
+			return fmt.Errorf("Invalid serial_number %s", j.SerialNumber)
+		}
+	}
+	s.SignatureAlgorithm = parseSignatureAlgorithm(j.SignatureAlgorithm)
+	if j.VerifyError != "" {
+		s.VerifyError = errors.New(j.VerifyError)
+	}
+	if len(j.Raw) > 0 {
+		s.Certificate, err = x509.ParseCertificate(j.Raw)
+		if err != nil {
+			return fmt.Errorf("Invalid raw certificate - %s", err)
+		}
+	}
+	return nil
+}
+
+// parseSignatureAlgorithm will return the x509.SignatureAlgorithm matching the provided name, or
+// x509.UnknownSignatureAlgorithm if no algorithm matches.
+func parseSignatureAlgorithm(name string) x509.SignatureAlgorithm {
+	for alg := x509.MD2WithRSA; alg <= x509.PureEd25519; alg++ {
+		if alg.String() == name {
+			return alg
+		}
+	}
+	return x509.UnknownSignatureAlgorithm
+}
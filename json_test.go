@@ -0,0 +1,109 @@
+package hazexpired
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test encoding and decoding CertificateStatus as JSON
+func TestCertificateStatusJSON(t *testing.T) {
+	status := &CertificateStatus{
+		ExpiredNow:               true,
+		ExpiresInDays:            -3,
+		ExpirationDate:           time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		NotBefore:                time.Date(2023, 5, 1, 12, 30, 0, 0, time.UTC),
+		LifetimeRemainingPercent: 12.5,
+		Signature:                []byte{0xde, 0xad, 0xbe, 0xef},
+		SerialNumber:             big.NewInt(42),
+		Subject:                  "CN=example.com",
+		Issuer:                   "CN=Example CA",
+		DNSNames:                 []string{"example.com", "www.example.com"},
+		SignatureAlgorithm:       x509.SHA256WithRSA,
+		Fingerprint:              "DE:AD:BE:EF",
+		SelfSigned:               true,
+		Source:                   "example.com:443",
+		VerifyError:              errors.New("x509: certificate has expired"),
+	}
+
+	b, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("Unexpected failure when encoding CertificateStatus - %s", err)
+	}
+
+	t.Run("Encoding", func(t *testing.T) {
+		for _, want := range []string{
+			`"expiration_date":"2024-05-01T12:30:00Z"`,
+			`"not_before":"2023-05-01T12:30:00Z"`,
+			`"lifetime_remaining_percent":12.5`,
+			`"serial_number":"42"`,
+			`"signature":"3q2+7w=="`,
+			`"signature_algorithm":"SHA256-RSA"`,
+			`"fingerprint":"DE:AD:BE:EF"`,
+			`"verify_error":"x509: certificate has expired"`,
+		} {
+			if !strings.Contains(string(b), want) {
+				t.Errorf("Expected JSON to contain %s got %s", want, b)
+			}
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var decoded CertificateStatus
+		err := json.Unmarshal(b, &decoded)
+		if err != nil {
+			t.Fatalf("Unexpected failure when decoding CertificateStatus - %s", err)
+		}
+		if decoded.VerifyError == nil || decoded.VerifyError.Error() != status.VerifyError.Error() {
+			t.Errorf("Unexpected VerifyError after round trip got %v", decoded.VerifyError)
+		}
+		decoded.VerifyError = status.VerifyError
+		if !reflect.DeepEqual(&decoded, status) {
+			t.Errorf("Unexpected CertificateStatus after round trip, expected %+v got %+v", status, decoded)
+		}
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+		if err != nil {
+			t.Fatalf("Unable to generate test certificates - %s", err)
+		}
+		b, err := json.Marshal(newCertificateStatus(chain.leaf, time.Now()))
+		if err != nil {
+			t.Fatalf("Unexpected failure when encoding CertificateStatus - %s", err)
+		}
+		var decoded CertificateStatus
+		err = json.Unmarshal(b, &decoded)
+		if err != nil {
+			t.Fatalf("Unexpected failure when decoding CertificateStatus - %s", err)
+		}
+		if !bytes.Equal(decoded.Raw, chain.leaf.Raw) {
+			t.Errorf("Unexpected Raw after round trip")
+		}
+		if decoded.Certificate == nil || !decoded.Certificate.Equal(chain.leaf) {
+			t.Errorf("Expected Certificate to be parsed from Raw after round trip, got %v", decoded.Certificate)
+		}
+	})
+
+	t.Run("InvalidRaw", func(t *testing.T) {
+		var decoded CertificateStatus
+		err := json.Unmarshal([]byte(`{"raw":"3q2+7w=="}`), &decoded)
+		if err == nil {
+			t.Errorf("Expected failure when decoding an invalid raw certificate, err is nil")
+		}
+	})
+
+	t.Run("InvalidSerialNumber", func(t *testing.T) {
+		var decoded CertificateStatus
+		err := json.Unmarshal([]byte(`{"serial_number":"not-a-number"}`), &decoded)
+		if err == nil {
+			t.Errorf("Expected failure when decoding an invalid serial number, err is nil")
+		}
+	})
+}
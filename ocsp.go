@@ -0,0 +1,360 @@
+package hazexpired
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// maxOCSPResponseSize limits the size of OCSP responses read from a responder.
+const maxOCSPResponseSize = 1 << 20
+
+var (
+	// oidSHA1 identifies the SHA-1 hash used within OCSP certificate IDs
+	oidSHA1 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+
+	// oidOCSPBasic identifies a basic OCSP response
+	oidOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+)
+
+// ocspSignatureAlgorithms maps signature algorithm OIDs used by OCSP responders to their x509.SignatureAlgorithm.
+var ocspSignatureAlgorithms = map[string]x509.SignatureAlgorithm{
+	"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+	"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+	"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+	"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+	"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+	"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+	"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+	"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+	"1.3.101.112":           x509.PureEd25519,
+}
+
+// OCSPCertStatus is the revocation status of a certificate reported by an OCSP responder.
+type OCSPCertStatus int
+
+const (
+	// OCSPGood indicates the responder reports the certificate is not revoked
+	OCSPGood OCSPCertStatus = iota
+
+	// OCSPRevoked indicates the responder reports the certificate is revoked
+	OCSPRevoked
+
+	// OCSPUnknown indicates the responder does not know about the certificate
+	OCSPUnknown
+)
+
+// String returns the name of the OCSP certificate status.
+func (s OCSPCertStatus) String() string {
+	switch s {
+	case OCSPGood:
+		return "good"
+	case OCSPRevoked:
+		return "revoked"
+	case OCSPUnknown:
+		return "unknown"
+	}
+	return fmt.Sprintf("OCSPCertStatus(%d)", int(s))
+}
+
+// OCSPStatus represents the revocation status of a certificate as reported by its OCSP responder.
+type OCSPStatus struct {
+	// Status is the revocation status of the certificate
+	Status OCSPCertStatus
+
+	// Responder is the OCSP responder URL that was queried
+	Responder string
+
+	// ProducedAt is when the responder signed the response
+	ProducedAt time.Time
+
+	// ThisUpdate is when the reported status was known to be correct
+	ThisUpdate time.Time
+
+	// NextUpdate is when newer status information will be available, this is zero if the responder did not provide it
+	NextUpdate time.Time
+
+	// RevokedAt is when the certificate was revoked, this is zero unless Status is OCSPRevoked
+	RevokedAt time.Time
+}
+
+// CheckOCSP will query the OCSP responder listed within the remote system's leaf certificate and return the leaf's
+// revocation status. The issuer certificate needed to build the request is taken from the fetched chain, and the
+// response signature is verified against the issuer or a responder certificate it has delegated to.
+func CheckOCSP(address string, opts ...Option) (OCSPStatus, error) {
+	return CheckOCSPContext(context.Background(), address, opts...)
+}
+
+// CheckOCSPContext is the same as CheckOCSP but uses the provided context to cancel or set a deadline on fetching the certificate chain and OCSP response.
+func CheckOCSPContext(ctx context.Context, address string, opts ...Option) (OCSPStatus, error) {
+	cfg := newConfig(opts...)
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return OCSPStatus{}, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	if len(chain) == 0 {
+		return OCSPStatus{}, fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	if len(chain) < 2 {
+		return OCSPStatus{}, fmt.Errorf("Issuer certificate not presented by outbound address %s, it is required for OCSP", address)
+	}
+	return cfg.checkOCSP(ctx, chain[0].Certificate, chain[1].Certificate)
+}
+
+// HasStapledOCSP will return true if the remote system stapled an OCSP response to the TLS handshake. Stapling saves
+// clients a request to the OCSP responder and is relied upon by many EV flows, checking for it catches stapling
+// regressions. The stapled response is not parsed or verified, it is available as OCSPResponse from
+// FetchConnectionStatus. An error is returned, along with false, when the handshake fails.
+func HasStapledOCSP(address string, opts ...Option) (bool, error) {
+	return HasStapledOCSPContext(context.Background(), address, opts...)
+}
+
+// HasStapledOCSPContext is the same as HasStapledOCSP but uses the provided context to cancel or set a deadline on the connection and TLS handshake.
+func HasStapledOCSPContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	conn, err := FetchConnectionStatusContext(ctx, address, opts...)
+	if err != nil {
+		return false, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	return len(conn.OCSPResponse) > 0, nil
+}
+
+// checkOCSP will send an OCSP request for the certificate to its first listed responder and parse the response.
+func (cfg *config) checkOCSP(ctx context.Context, cert, issuer *x509.Certificate) (OCSPStatus, error) {
+	if len(cert.OCSPServer) == 0 {
+		return OCSPStatus{}, fmt.Errorf("Certificate %s does not list an OCSP responder", cert.Subject)
+	}
+	responder := cert.OCSPServer[0]
+
+	body, err := newOCSPRequest(cert, issuer)
+	if err != nil {
+		return OCSPStatus{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responder, bytes.NewReader(body))
+	if err != nil {
+		return OCSPStatus{}, fmt.Errorf("Could not create OCSP request for %s - %s", responder, err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := cfg.httpClient().Do(req)
+	if err != nil {
+		return OCSPStatus{}, fmt.Errorf("Could not query OCSP responder %s - %w", responder, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return OCSPStatus{}, fmt.Errorf("OCSP responder %s returned %s", responder, resp.Status)
+	}
+	der, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return OCSPStatus{}, fmt.Errorf("Could not read OCSP response from %s - %s", responder, err)
+	}
+
+	status, err := parseOCSPResponse(der, cert, issuer)
+	if err != nil {
+		return OCSPStatus{}, fmt.Errorf("Invalid OCSP response from %s - %w", responder, err)
+	}
+	status.Responder = responder
+	return status, nil
+}
+
+// ocspRequest is the ASN.1 structure of an OCSP request as defined by RFC 6960.
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+// tbsRequest is the portion of an OCSP request which may be signed.
+type tbsRequest struct {
+	Version       int              `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName pkix.RDNSequence `asn1:"explicit,tag:1,optional"`
+	RequestList   []request
+}
+
+// request identifies a single certificate within an OCSP request.
+type request struct {
+	Cert certID
+}
+
+// certID identifies a certificate by its issuer and serial number.
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+// ocspResponse is the ASN.1 structure of an OCSP response as defined by RFC 6960.
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+// responseBytes holds the type and encoded body of an OCSP response.
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+// basicResponse is a signed basic OCSP response.
+type basicResponse struct {
+	TBSResponseData    responseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+// responseData is the signed portion of a basic OCSP response.
+type responseData struct {
+	Raw            asn1.RawContent
+	Version        int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID asn1.RawValue
+	ProducedAt     time.Time `asn1:"generalized"`
+	Responses      []singleResponse
+}
+
+// singleResponse is the status of a single certificate within a basic OCSP response.
+type singleResponse struct {
+	CertID           certID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          revokedInfo      `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// revokedInfo describes when and why a certificate was revoked.
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// newCertID will build the OCSP certificate ID for the certificate, identifying its issuer by SHA-1 hashes of the
+// issuer's subject and public key.
+func newCertID(cert, issuer *x509.Certificate) (certID, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	_, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki)
+	if err != nil {
+		return certID{}, fmt.Errorf("Could not parse issuer public key - %s", err)
+	}
+
+	h := crypto.SHA1.New()
+	h.Write(issuer.RawSubject)
+	nameHash := h.Sum(nil)
+	h.Reset()
+	h.Write(spki.PublicKey.RightAlign())
+	keyHash := h.Sum(nil)
+
+	return certID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidSHA1,
+			Parameters: asn1.RawValue{Tag: asn1.TagNull},
+		},
+		NameHash:      nameHash,
+		IssuerKeyHash: keyHash,
+		SerialNumber:  cert.SerialNumber,
+	}, nil
+}
+
+// newOCSPRequest will create a DER encoded OCSP request for the certificate.
+func newOCSPRequest(cert, issuer *x509.Certificate) ([]byte, error) {
+	id, err := newCertID(cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ocspRequest{
+		TBSRequest: tbsRequest{
+			RequestList: []request{{Cert: id}},
+		},
+	})
+}
+
+// parseOCSPResponse will parse a DER encoded OCSP response, verify its signature, and return the status of the certificate.
+func parseOCSPResponse(der []byte, cert, issuer *x509.Certificate) (OCSPStatus, error) {
+	var resp ocspResponse
+	_, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return OCSPStatus{}, err
+	}
+	if resp.Status != 0 {
+		return OCSPStatus{}, fmt.Errorf("responder returned error status %d", resp.Status)
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasic) {
+		return OCSPStatus{}, fmt.Errorf("unsupported response type %s", resp.Response.ResponseType)
+	}
+
+	var basic basicResponse
+	_, err = asn1.Unmarshal(resp.Response.Response, &basic)
+	if err != nil {
+		return OCSPStatus{}, err
+	}
+
+	// Verify the response was signed by the issuer or a responder certificate issued by it
+	signer := issuer
+	if len(basic.Certificates) > 0 {
+		signer, err = x509.ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return OCSPStatus{}, fmt.Errorf("could not parse responder certificate - %s", err)
+		}
+		if !bytes.Equal(signer.Raw, issuer.Raw) {
+			err = signer.CheckSignatureFrom(issuer)
+			if err != nil {
+				return OCSPStatus{}, fmt.Errorf("responder certificate not issued by the certificate issuer - %w", err)
+			}
+			if !hasExtKeyUsage(signer, x509.ExtKeyUsageOCSPSigning) {
+				return OCSPStatus{}, errors.New("responder certificate is not authorized for OCSP signing")
+			}
+		}
+	}
+	alg, ok := ocspSignatureAlgorithms[basic.SignatureAlgorithm.Algorithm.String()]
+	if !ok {
+		return OCSPStatus{}, fmt.Errorf("unsupported signature algorithm %s", basic.SignatureAlgorithm.Algorithm)
+	}
+	err = signer.CheckSignature(alg, basic.TBSResponseData.Raw, basic.Signature.RightAlign())
+	if err != nil {
+		return OCSPStatus{}, fmt.Errorf("signature verification failed - %w", err)
+	}
+
+	for _, r := range basic.TBSResponseData.Responses {
+		if r.CertID.SerialNumber == nil || r.CertID.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			continue
+		}
+		status := OCSPStatus{
+			ProducedAt: basic.TBSResponseData.ProducedAt,
+			ThisUpdate: r.ThisUpdate,
+			NextUpdate: r.NextUpdate,
+		}
+		switch {
+		case bool(r.Good):
+			status.Status = OCSPGood
+		case !r.Revoked.RevocationTime.IsZero():
+			status.Status = OCSPRevoked
+			status.RevokedAt = r.Revoked.RevocationTime
+		default:
+			status.Status = OCSPUnknown
+		}
+		return status, nil
+	}
+	return OCSPStatus{}, fmt.Errorf("no status for serial number %s", cert.SerialNumber)
+}
+
+// hasExtKeyUsage will return true if the certificate permits the extended key usage.
+func hasExtKeyUsage(cert *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
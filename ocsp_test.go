@@ -0,0 +1,213 @@
+package hazexpired
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// ocspResponder is a test OCSP responder which reports every requested certificate as good or revoked, signing
+// responses with the provided key
+func ocspResponder(ca *x509.Certificate, key *ecdsa.PrivateKey, revoked bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req ocspRequest
+		_, err = asn1.Unmarshal(body, &req)
+		if err != nil || len(req.TBSRequest.RequestList) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		single := singleResponse{
+			CertID:     req.TBSRequest.RequestList[0].Cert,
+			ThisUpdate: now,
+			NextUpdate: now.Add(24 * time.Hour),
+		}
+		if revoked {
+			single.Revoked = revokedInfo{RevocationTime: now.Add(-time.Hour)}
+		} else {
+			single.Good = true
+		}
+		tbs, _ := asn1.Marshal(responseData{
+			RawResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: ca.RawSubject},
+			ProducedAt:     now,
+			Responses:      []singleResponse{single},
+		})
+
+		digest := sha256.Sum256(tbs)
+		sig, _ := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		basic, _ := asn1.Marshal(basicResponse{
+			TBSResponseData:    responseData{Raw: tbs},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+			Signature:          asn1.BitString{Bytes: sig, BitLength: len(sig) * 8},
+		})
+		resp, _ := asn1.Marshal(ocspResponse{
+			Response: responseBytes{ResponseType: oidOCSPBasic, Response: basic},
+		})
+
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		_, _ = w.Write(resp)
+	}
+}
+
+// Test checking the OCSP revocation status of the leaf certificate
+func TestCheckOCSP(t *testing.T) {
+	responder := httptest.NewServer(nil)
+	defer responder.Close()
+
+	// Create a chain whose leaf lists the test responder
+	chain, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.OCSPServer = []string{responder.URL}
+	})
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{chain.cert}})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Good", func(t *testing.T) {
+		responder.Config.Handler = ocspResponder(chain.ca, chain.caKey, false)
+		status, err := CheckOCSP("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckOCSP - %s", err)
+		}
+		if status.Status != OCSPGood {
+			t.Errorf("Unexpected OCSP status, expected good got %s", status.Status)
+		}
+		if status.NextUpdate.IsZero() || status.Responder != responder.URL {
+			t.Errorf("Unexpected OCSP status details - %+v", status)
+		}
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		responder.Config.Handler = ocspResponder(chain.ca, chain.caKey, true)
+		status, err := CheckOCSP("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckOCSP - %s", err)
+		}
+		if status.Status != OCSPRevoked || status.RevokedAt.IsZero() {
+			t.Errorf("Unexpected OCSP status, expected revoked got %+v", status)
+		}
+	})
+
+	t.Run("InvalidSignature", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Unable to generate key - %s", err)
+		}
+		responder.Config.Handler = ocspResponder(chain.ca, key, false)
+		_, err = CheckOCSP("127.0.0.1:9000")
+		if err == nil {
+			t.Errorf("Expected failure when the OCSP response is signed by the wrong key, err is nil")
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := CheckOCSP("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test checking OCSP when the issuer is not presented
+func TestCheckOCSPNoIssuer(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	_, err = CheckOCSP("127.0.0.1:9000")
+	if err == nil {
+		t.Errorf("Expected failure when the issuer certificate is not presented, err is nil")
+	}
+}
+
+// Test detecting an OCSP response stapled to the handshake
+func TestHasStapledOCSP(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	staple := []byte("stapled OCSP response")
+
+	tt := []struct {
+		name    string
+		staple  []byte
+		stapled bool
+	}{
+		{"Stapled", staple, true},
+		{"NotStapled", nil, false},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			cert := chain.cert
+			cert.OCSPStaple = c.staple
+
+			// Start Listener
+			l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{cert}})
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			time.Sleep(30 * time.Millisecond)
+			defer l.Close()
+
+			stapled, err := HasStapledOCSP("127.0.0.1:9000")
+			if err != nil {
+				t.Fatalf("Unexpected failure when calling HasStapledOCSP - %s", err)
+			}
+			if stapled != c.stapled {
+				t.Errorf("Unexpected result from HasStapledOCSP, expected %t got %t", c.stapled, stapled)
+			}
+
+			conn, err := FetchConnectionStatus("127.0.0.1:9000")
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+			}
+			if string(conn.OCSPResponse) != string(c.staple) {
+				t.Errorf("Unexpected stapled OCSP response, expected %q got %q", c.staple, conn.OCSPResponse)
+			}
+		})
+	}
+
+	t.Run("FetchError", func(t *testing.T) {
+		stapled, err := HasStapledOCSP("127.0.0.1:1")
+		if err == nil || stapled {
+			t.Errorf("Expected HasStapledOCSP to fail with an unreachable address, got %t %v", stapled, err)
+		}
+	})
+}
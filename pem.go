@@ -0,0 +1,147 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FetchChainFromPEM will parse one or more PEM encoded certificates and return a CertificateStatus object for each
+// certificate found. Non-certificate PEM blocks, such as private keys, are ignored. Certificates which fail to parse
+// are skipped, the remaining certificates are returned along with an error joining each parse failure. Remote
+// systems presenting a malformed certificate fail the TLS handshake, so FetchChain cannot return partial results.
+func FetchChainFromPEM(data []byte) ([]*CertificateStatus, error) {
+	var chain []*CertificateStatus
+	var errs []error
+	var count int
+	now := time.Now()
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		count++
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Could not parse PEM certificate %d - %w", count, err))
+			continue
+		}
+		status := newCertificateStatus(cert, now)
+		status.Role = certificateRole(cert, len(chain))
+		chain = append(chain, status)
+	}
+	if len(chain) == 0 && len(errs) == 0 {
+		return nil, fmt.Errorf("No CERTIFICATE blocks found in PEM data")
+	}
+	return chain, errors.Join(errs...)
+}
+
+// FetchChainFromBytes will parse raw certificate data in either PEM or DER encoding, such as piped through stdin, and
+// return a CertificateStatus object for each certificate. The encoding is detected automatically, data containing a
+// PEM block is parsed by FetchChainFromPEM while anything else is parsed as one or more concatenated DER
+// certificates. An error is returned when the data is neither valid PEM nor valid DER.
+func FetchChainFromBytes(data []byte) ([]*CertificateStatus, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		return FetchChainFromPEM(data)
+	}
+	certs, err := x509.ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse certificate data as PEM or DER - %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("No certificates found in certificate data")
+	}
+	var chain []*CertificateStatus
+	now := time.Now()
+	for i, cert := range certs {
+		status := newCertificateStatus(cert, now)
+		status.Role = certificateRole(cert, i)
+		chain = append(chain, status)
+	}
+	return chain, nil
+}
+
+// FetchChainFromFile will read a PEM encoded file from disk and return a CertificateStatus object for each certificate
+// within it. Like FetchChainFromPEM, certificates which parse are returned alongside an error for those which do not.
+func FetchChainFromFile(path string) ([]*CertificateStatus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read certificate file %s - %s", path, err)
+	}
+	chain, err := FetchChainFromPEM(data)
+	for _, cert := range chain {
+		cert.Source = path
+	}
+	if err != nil {
+		return chain, fmt.Errorf("Could not load certificates from %s - %w", path, err)
+	}
+	return chain, nil
+}
+
+// FetchChainFromFiles will read each PEM encoded file and return a CertificateStatus object for every certificate
+// across all of the files, each with its Source set to the file path. Files which cannot be read or contain no
+// certificates are skipped, the certificates from the remaining files are returned along with an error joining
+// each failure. Certificates which parse are kept from files containing malformed certificates. Use SoonestExpiry
+// to find which certificate expires first.
+func FetchChainFromFiles(paths ...string) ([]*CertificateStatus, error) {
+	var chain []*CertificateStatus
+	var errs []error
+	for _, path := range paths {
+		certs, err := FetchChainFromFile(path)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		chain = append(chain, certs...)
+	}
+	return chain, errors.Join(errs...)
+}
+
+// FetchChainFromDir will read every file within the directory, following symlinks but not including subdirectories,
+// in the same way as FetchChainFromFiles.
+func FetchChainFromDir(dir string) ([]*CertificateStatus, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read certificate directory %s - %w", dir, err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		// Follow symlinks, such as those of mounted Kubernetes secrets, skipping only directories
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return FetchChainFromFiles(paths...)
+}
+
+// ChainPEM will fetch the remote system's certificate chain and return it as concatenated PEM encoded certificates,
+// exactly as presented and in the same order, for saving alongside bug reports or offline analysis. Issuers downloaded
+// with WithFetchIssuers are not included, and WithRoles does not apply. The result can be read back with
+// FetchChainFromPEM.
+func ChainPEM(address string, opts ...Option) ([]byte, error) {
+	return ChainPEMContext(context.Background(), address, opts...)
+}
+
+// ChainPEMContext is the same as ChainPEM but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func ChainPEMContext(ctx context.Context, address string, opts ...Option) ([]byte, error) {
+	state, err := newConfig(opts...).fetchState(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	var data []byte
+	for _, cert := range state.PeerCertificates {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return data, nil
+}
@@ -0,0 +1,255 @@
+package hazexpired
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test checking certificates from PEM data without a network connection
+func TestFetchChainFromPEM(t *testing.T) {
+	// Create cert/key pairs
+	good, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+	expired, _, err := genCerts(time.Now().Truncate(24 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	t.Run("MultipleCerts", func(t *testing.T) {
+		data := append(append(append([]byte{}, good...), key...), expired...)
+		chain, err := FetchChainFromPEM(data)
+		if err != nil {
+			t.Fatalf("Unexpected failure when parsing PEM data - %s", err)
+		}
+		if len(chain) != 2 {
+			t.Fatalf("Unexpected number of certificates, expected 2 got %d", len(chain))
+		}
+		if chain[0].ExpiredNow || !chain[1].ExpiredNow {
+			t.Errorf("Unexpected expiration status, expected [false true] got [%t %t]", chain[0].ExpiredNow, chain[1].ExpiredNow)
+		}
+	})
+
+	t.Run("MalformedCert", func(t *testing.T) {
+		malformed := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})
+		data := append(append(append([]byte{}, good...), malformed...), expired...)
+		chain, err := FetchChainFromPEM(data)
+		if err == nil || !strings.Contains(err.Error(), "certificate 2") {
+			t.Errorf("Expected failure identifying the malformed certificate, got %v", err)
+		}
+		if len(chain) != 2 {
+			t.Fatalf("Unexpected number of certificates, expected 2 got %d", len(chain))
+		}
+		if chain[0].ExpiredNow || !chain[1].ExpiredNow {
+			t.Errorf("Unexpected expiration status, expected [false true] got [%t %t]", chain[0].ExpiredNow, chain[1].ExpiredNow)
+		}
+
+		path := filepath.Join(t.TempDir(), "partial.pem")
+		err = os.WriteFile(path, data, 0600)
+		if err != nil {
+			t.Fatalf("Unable to write test certificate - %s", err)
+		}
+		chain, err = FetchChainFromFiles(path)
+		if err == nil {
+			t.Errorf("Expected failure when loading a file with a malformed certificate, err is nil")
+		}
+		if len(chain) != 2 || chain[0].Source != path {
+			t.Errorf("Unexpected partial Certificate Chain loaded from file - %+v", chain)
+		}
+	})
+
+	t.Run("OnlyMalformed", func(t *testing.T) {
+		chain, err := FetchChainFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")}))
+		if err == nil || len(chain) != 0 {
+			t.Errorf("Expected failure and no certificates from only a malformed certificate, got %d and %v", len(chain), err)
+		}
+	})
+
+	t.Run("NoCertificates", func(t *testing.T) {
+		_, err := FetchChainFromPEM(key)
+		if err == nil {
+			t.Errorf("Expected failure when PEM data has no certificates, err is nil")
+		}
+	})
+
+	t.Run("FromFile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cert.pem")
+		err := os.WriteFile(path, good, 0600)
+		if err != nil {
+			t.Fatalf("Unable to write test certificate - %s", err)
+		}
+		chain, err := FetchChainFromFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected failure when loading certificate file - %s", err)
+		}
+		if len(chain) != 1 || chain[0].ExpiredNow || chain[0].Source != path {
+			t.Errorf("Unexpected Certificate Chain loaded from file - %+v", chain)
+		}
+	})
+
+	t.Run("FromDir", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, data := range map[string][]byte{"good.pem": good, "expired.pem": expired, "key.pem": key, "README": []byte("not a cert")} {
+			err := os.WriteFile(filepath.Join(dir, name), data, 0600)
+			if err != nil {
+				t.Fatalf("Unable to write test file - %s", err)
+			}
+		}
+		err := os.Mkdir(filepath.Join(dir, "subdir"), 0700)
+		if err != nil {
+			t.Fatalf("Unable to create test directory - %s", err)
+		}
+
+		chain, err := FetchChainFromDir(dir)
+		if err == nil {
+			t.Errorf("Expected failure for files without certificates, err is nil")
+		}
+		if len(chain) != 2 {
+			t.Fatalf("Unexpected number of certificates, expected 2 got %d", len(chain))
+		}
+		soonest := SoonestExpiry(chain)
+		if soonest.Source != filepath.Join(dir, "expired.pem") {
+			t.Errorf("Unexpected soonest expiring certificate, expected expired.pem got %s", soonest.Source)
+		}
+	})
+
+	t.Run("FromDirSymlinks", func(t *testing.T) {
+		dir := t.TempDir()
+		data := filepath.Join(dir, "..data")
+		err := os.Mkdir(data, 0700)
+		if err != nil {
+			t.Fatalf("Unable to create test directory - %s", err)
+		}
+		err = os.WriteFile(filepath.Join(data, "tls.crt"), good, 0600)
+		if err != nil {
+			t.Fatalf("Unable to write test file - %s", err)
+		}
+		err = os.Symlink(filepath.Join("..data", "tls.crt"), filepath.Join(dir, "tls.crt"))
+		if err != nil {
+			t.Fatalf("Unable to create test symlink - %s", err)
+		}
+		err = os.Symlink("..data", filepath.Join(dir, "current"))
+		if err != nil {
+			t.Fatalf("Unable to create test symlink - %s", err)
+		}
+
+		chain, err := FetchChainFromDir(dir)
+		if err != nil {
+			t.Fatalf("Unexpected failure when loading a directory of symlinks - %s", err)
+		}
+		if len(chain) != 1 || chain[0].Source != filepath.Join(dir, "tls.crt") {
+			t.Errorf("Unexpected certificates, expected only the symlinked tls.crt got %+v", chain)
+		}
+	})
+
+	t.Run("MissingDir", func(t *testing.T) {
+		_, err := FetchChainFromDir(filepath.Join(t.TempDir(), "missing"))
+		if err == nil {
+			t.Errorf("Expected failure when loading a missing directory, err is nil")
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		_, err := FetchChainFromFile(filepath.Join(t.TempDir(), "missing.pem"))
+		if err == nil {
+			t.Errorf("Expected failure when loading a missing file, err is nil")
+		}
+	})
+}
+
+// Test exporting the fetched certificate chain as PEM
+func TestChainPEM(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+
+	data, err := ChainPEM("example.com", dialer, WithRoles(RoleLeaf))
+	if err != nil {
+		t.Fatalf("Unexpected failure when calling ChainPEM - %s", err)
+	}
+
+	var blocks []*pem.Block
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		blocks = append(blocks, block)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("Unexpected number of PEM blocks, expected 2 got %d", len(blocks))
+	}
+	for i, cert := range []*x509.Certificate{chain.leaf, chain.ca} {
+		if blocks[i].Type != "CERTIFICATE" || !bytes.Equal(blocks[i].Bytes, cert.Raw) {
+			t.Errorf("Unexpected PEM block %d, expected the certificate as presented", i)
+		}
+	}
+
+	certs, err := FetchChainFromPEM(data)
+	if err != nil || len(certs) != 2 {
+		t.Errorf("Unexpected failure reading back the exported PEM - %d certificates, %v", len(certs), err)
+	}
+
+	t.Run("FetchError", func(t *testing.T) {
+		_, err := ChainPEM("127.0.0.1:1")
+		if err == nil {
+			t.Errorf("Expected ChainPEM to fail with an unreachable address, err is nil")
+		}
+	})
+}
+
+// Test parsing certificate data with automatic PEM or DER detection
+func TestFetchChainFromBytes(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	var pemData []byte
+	for _, cert := range []*x509.Certificate{chain.leaf, chain.ca} {
+		pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+
+	tt := []struct {
+		name  string
+		data  []byte
+		count int
+		err   bool
+	}{
+		{"PEM", pemData, 2, false},
+		{"DER", chain.leaf.Raw, 1, false},
+		{"ConcatenatedDER", append(append([]byte{}, chain.leaf.Raw...), chain.ca.Raw...), 2, false},
+		{"Garbage", []byte("not a certificate"), 0, true},
+		{"Empty", nil, 0, true},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			certs, err := FetchChainFromBytes(c.data)
+			if c.err && err == nil {
+				t.Errorf("Expected failure when parsing certificate data, err is nil")
+			}
+			if !c.err && err != nil {
+				t.Errorf("Unexpected failure when parsing certificate data - %s", err)
+			}
+			if len(certs) != c.count {
+				t.Fatalf("Unexpected number of certificates, expected %d got %d", c.count, len(certs))
+			}
+			if c.count > 0 && (!certs[0].Certificate.Equal(chain.leaf) || certs[0].Role != RoleLeaf) {
+				t.Errorf("Unexpected first certificate got %s %s", certs[0].Subject, certs[0].Role)
+			}
+			if c.count > 1 && certs[1].Role != RoleRoot {
+				t.Errorf("Unexpected role for the CA, expected %s got %s", RoleRoot, certs[1].Role)
+			}
+		})
+	}
+}
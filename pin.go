@@ -0,0 +1,31 @@
+package hazexpired
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// ErrPinMismatch is returned when WithPinnedSPKI is provided and the remote system's leaf certificate public key does
+// not match any of the pins.
+var ErrPinMismatch = errors.New("Public key does not match any pin")
+
+// checkPins will return an error wrapping ErrPinMismatch if pins are configured and the SHA-256 hash of the leaf
+// certificate's SubjectPublicKeyInfo does not match any of them.
+func (cfg *config) checkPins(certs []*x509.Certificate, address string) error {
+	if len(cfg.pinnedSPKI) == 0 {
+		return nil
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("%w by outbound address %s", ErrNoCertificates, address)
+	}
+	sum := sha256.Sum256(certs[0].RawSubjectPublicKeyInfo)
+	for _, pin := range cfg.pinnedSPKI {
+		if bytes.Equal(pin, sum[:]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w from outbound address %s, got sha256 %x", ErrPinMismatch, address, sum)
+}
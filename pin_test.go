@@ -0,0 +1,44 @@
+package hazexpired
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test pinning the leaf certificate public key
+func TestPinnedSPKI(t *testing.T) {
+	// Create cert/key pair
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+	pin := sha256.Sum256(chain.leaf.RawSubjectPublicKeyInfo)
+	other := sha256.Sum256(chain.ca.RawSubjectPublicKeyInfo)
+
+	// Start Listener
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{chain.cert}})
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Match", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithPinnedSPKI([][]byte{other[:], pin[:]}))
+		if err != nil {
+			t.Errorf("Unexpected failure when fetching Certificate Chain with a matching pin - %s", err)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		_, err := Expired("127.0.0.1:9000", WithPinnedSPKI([][]byte{other[:]}))
+		if !errors.Is(err, ErrPinMismatch) {
+			t.Errorf("Expected ErrPinMismatch when the leaf does not match the pin, got %s", err)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package hazexpired
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrProxyRejected is returned when an HTTP or SOCKS5 proxy refuses a CONNECT request, or rejects the credentials offered.
+var ErrProxyRejected = errors.New("Proxy rejected CONNECT request")
+
+// dialHTTPProxy will connect to the HTTP proxy and issue a CONNECT request for the address, returning a connection
+// tunneled through the proxy to the address. Credentials within the proxy URL are sent using basic authentication.
+func dialHTTPProxy(ctx context.Context, d *net.Dialer, proxyURL, address string) (net.Conn, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid proxy URL - %s", err)
+	}
+	if u.Scheme != "http" {
+		return nil, fmt.Errorf("Unsupported proxy scheme %q, only http is supported", u.Scheme)
+	}
+	proxyAddr := u.Host
+	if u.Port() == "" {
+		proxyAddr = net.JoinHostPort(u.Hostname(), "80")
+	}
+
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not connect to proxy %s - %w", proxyAddr, err)
+	}
+
+	// Interrupt the CONNECT exchange once the context is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+creds)
+	}
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Could not send CONNECT request to proxy %s - %w", proxyAddr, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Could not read CONNECT response from proxy %s - %w", proxyAddr, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("%w to %s - %s", ErrProxyRejected, address, resp.Status)
+	}
+
+	// Preserve anything the remote system sent that was read along with the CONNECT response
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn which drains data already buffered by a bufio.Reader before reading from the connection.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read will read from the buffered reader, which reads from the underlying connection once its buffer is drained.
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,72 @@
+package hazexpired
+
+import (
+	"crypto/x509"
+	"slices"
+	"sync"
+)
+
+// PublicCAFunc reports whether a certificate chain, leaf first as presented by the remote system, was issued by a
+// public CA rather than a private or internal PKI, see WithPublicCAFunc.
+type PublicCAFunc func(chain []*x509.Certificate) bool
+
+// publicCAOrganizations are the issuer organization names of well-known public CAs, including their intermediates.
+var publicCAOrganizations = []string{
+	"Amazon",
+	"Buypass AS-983163327",
+	"Certum",
+	"DigiCert Inc",
+	"Entrust, Inc.",
+	"GlobalSign nv-sa",
+	"GoDaddy.com, Inc.",
+	"Google Trust Services",
+	"Google Trust Services LLC",
+	"Let's Encrypt",
+	"Microsoft Corporation",
+	"Sectigo Limited",
+	"SSL Corporation",
+	"Starfield Technologies, Inc.",
+	"ZeroSSL",
+}
+
+// systemRoots loads the system roots once for IsPublicCA rather than copying the pool on every call.
+var systemRoots = sync.OnceValues(x509.SystemCertPool)
+
+// IsPublicCA is the PublicCAFunc used by WithPublicCA to populate PublicCA on the leaf's CertificateStatus. A chain is
+// considered issued by a public CA when either of the following hold:
+//   - The leaf's issuer organization is one of a bundled set of well-known public CAs, such as Let's Encrypt,
+//     DigiCert Inc, or Google Trust Services.
+//   - The chain verifies against the system roots, ignoring expiry and hostname, as any CA in the system roots is
+//     publicly trusted.
+//
+// This is a heuristic for triage, separating problems with publicly issued certificates from those of an internal
+// PKI, and is not a trust decision. A private CA may use the same organization name as a public CA, and an internal
+// root installed into the system roots is considered public.
+func IsPublicCA(chain []*x509.Certificate) bool {
+	if len(chain) == 0 || chain[0] == nil {
+		return false
+	}
+	leaf := chain[0]
+	for _, org := range leaf.Issuer.Organization {
+		if slices.Contains(publicCAOrganizations, org) {
+			return true
+		}
+	}
+
+	roots, err := systemRoots()
+	if err != nil {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		// verify within the leaf's validity so expired certificates are still classified
+		CurrentTime: leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) / 2),
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
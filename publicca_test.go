@@ -0,0 +1,94 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+)
+
+// Test classifying chains as issued by a public or private CA
+func TestPublicCA(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	t.Run("PrivateCA", func(t *testing.T) {
+		if IsPublicCA([]*x509.Certificate{chain.leaf, chain.ca}) {
+			t.Errorf("Unexpected public CA classification for a private CA")
+		}
+	})
+
+	t.Run("PublicOrganization", func(t *testing.T) {
+		leaf := &x509.Certificate{Issuer: pkix.Name{Organization: []string{"Let's Encrypt"}, CommonName: "R11"}}
+		if !IsPublicCA([]*x509.Certificate{leaf}) {
+			t.Errorf("Expected a leaf issued by Let's Encrypt to be classified as a public CA")
+		}
+	})
+
+	t.Run("EmptyChain", func(t *testing.T) {
+		if IsPublicCA(nil) {
+			t.Errorf("Unexpected public CA classification for an empty chain")
+		}
+	})
+
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+
+	t.Run("FetchChain", func(t *testing.T) {
+		// a leaf issued by a recognized organization is only classified when enabled
+		leaf := *chain.leaf
+		leaf.Issuer = pkix.Name{Organization: []string{"Let's Encrypt"}, CommonName: "R11"}
+		public := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{&leaf, chain.ca}}}, nil
+		})
+
+		certs, err := FetchChain("example.com", public)
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if certs[0].PublicCA {
+			t.Errorf("Unexpected public CA classification without WithPublicCA")
+		}
+
+		certs, err = FetchChain("example.com", public, WithPublicCA())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if !certs[0].PublicCA {
+			t.Errorf("Expected a leaf issued by Let's Encrypt to be classified as a public CA with WithPublicCA")
+		}
+
+		certs, err = FetchChain("example.com", dialer, WithPublicCA())
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if certs[0].PublicCA {
+			t.Errorf("Unexpected public CA classification for a private CA")
+		}
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		var received []*x509.Certificate
+		certs, err := FetchChain("example.com", dialer, WithPublicCAFunc(func(c []*x509.Certificate) bool {
+			received = c
+			return true
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if !certs[0].PublicCA {
+			t.Errorf("Expected the override to classify the leaf as a public CA")
+		}
+		if certs[1].PublicCA {
+			t.Errorf("Unexpected public CA classification on a certificate other than the leaf")
+		}
+		if len(received) != 2 || received[0] != chain.leaf {
+			t.Errorf("Unexpected chain passed to the PublicCAFunc got %+v", received)
+		}
+	})
+}
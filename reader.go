@@ -0,0 +1,85 @@
+package hazexpired
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// checkResultJSON is the JSON line written by CheckReader for each address.
+type checkResultJSON struct {
+	Address string               `json:"address"`
+	Expired bool                 `json:"expired"`
+	Chain   []*CertificateStatus `json:"chain,omitempty"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// CheckReader will read newline-delimited addresses from r, check each concurrently, and write a JSON line to w for
+// each address as its check completes. Blank lines and lines starting with # are skipped. Each line holds the
+// address, whether a certificate within its chain is expired, the chain itself, and any error, which also marks the
+// address as expired. The number of simultaneous connections can be controlled with WithConcurrency.
+//
+//	{"address":"example.com:443","expired":false,"chain":[...]}
+func CheckReader(r io.Reader, w io.Writer, opts ...Option) error {
+	return CheckReaderContext(context.Background(), r, w, opts...)
+}
+
+// CheckReaderContext is the same as CheckReader but uses the provided context to cancel or set a deadline on fetching the certificate chains.
+func CheckReaderContext(ctx context.Context, r io.Reader, w io.Writer, opts ...Option) error {
+	cfg := newConfig(opts...)
+	if cfg.concurrency < 1 {
+		return fmt.Errorf("Batch concurrency must be at least 1, got %d", cfg.concurrency)
+	}
+
+	enc := json.NewEncoder(w)
+	var mu sync.Mutex
+	var writeErr error
+	var wg sync.WaitGroup
+
+	// Start a bounded pool of workers to check each address and write its result
+	queue := make(chan string)
+	for i := 0; i < cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for address := range queue {
+				result := checkResultJSON{Address: address}
+				chain, err := fetchChain(ctx, address, opts...)
+				result.Chain = chain
+				result.Expired = err != nil || anyExpired(chain)
+				if err != nil {
+					result.Error = err.Error()
+				}
+				mu.Lock()
+				if writeErr == nil {
+					writeErr = enc.Encode(result)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Queue each address, skipping blank lines and comments
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		address := strings.TrimSpace(scanner.Text())
+		if address == "" || strings.HasPrefix(address, "#") {
+			continue
+		}
+		queue <- address
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Could not read addresses - %w", err)
+	}
+	if writeErr != nil {
+		return fmt.Errorf("Could not write results - %w", writeErr)
+	}
+	return nil
+}
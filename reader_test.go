@@ -0,0 +1,63 @@
+package hazexpired
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test checking newline-delimited addresses and streaming JSON lines
+func TestCheckReader(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Truncate(24 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Addresses", func(t *testing.T) {
+		input := "# expiring certs\n127.0.0.1:9000\n\n  iamateapot:418  \n"
+		var out bytes.Buffer
+		err := CheckReader(strings.NewReader(input), &out, WithConcurrency(2))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckReader - %s", err)
+		}
+
+		results := make(map[string]checkResultJSON)
+		dec := json.NewDecoder(&out)
+		for dec.More() {
+			var r checkResultJSON
+			if err := dec.Decode(&r); err != nil {
+				t.Fatalf("Unexpected failure when decoding JSON line - %s", err)
+			}
+			results[r.Address] = r
+		}
+		if len(results) != 2 {
+			t.Fatalf("Unexpected number of results, expected 2 got %d", len(results))
+		}
+		if r := results["127.0.0.1:9000"]; !r.Expired || r.Error != "" || len(r.Chain) == 0 {
+			t.Errorf("Unexpected result for expired certificate - %+v", r)
+		}
+		if r := results["iamateapot:418"]; !r.Expired || r.Error == "" {
+			t.Errorf("Expected failure result for invalid address - %+v", r)
+		}
+	})
+
+	t.Run("InvalidConcurrency", func(t *testing.T) {
+		err := CheckReader(strings.NewReader("127.0.0.1:9000\n"), &bytes.Buffer{}, WithConcurrency(0))
+		if err == nil {
+			t.Errorf("Expected failure when calling with a concurrency of 0, err is nil")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// FetchChainAllIPs will resolve the host to all of its IPv4 and IPv6 addresses and fetch the certificate chain from
+// each, returning a BatchResult per IP. Hosts behind round-robin DNS or multiple load balancers may serve different
+// certificates from each IP, checking them all catches a single backend serving an old certificate. SNI is set to
+// the host for every connection unless chosen per IP with WithServerNameFunc, and the host is resolved using
+// WithResolver when provided. Addresses can be limited to a single stack with WithNetwork. A failure to fetch from an
+// individual IP is recorded within that IP's BatchResult, the number of simultaneous connections can be controlled
+// with WithConcurrency.
+func FetchChainAllIPs(host, port string, opts ...Option) (map[string]BatchResult, error) {
+	return FetchChainAllIPsContext(context.Background(), host, port, opts...)
+}
+
+// FetchChainAllIPsContext is the same as FetchChainAllIPs but uses the provided context to cancel or set a deadline on resolving the host and fetching the certificate chains.
+func FetchChainAllIPsContext(ctx context.Context, host, port string, opts ...Option) (map[string]BatchResult, error) {
+	name, err := toASCII(host)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(name) == nil {
+		opts = append([]Option{WithServerName(name)}, opts...)
+	}
+
+	cfg := newConfig(opts...)
+	r := cfg.resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	ips, err := r.LookupIPAddr(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to resolve host %s - %w", host, err)
+	}
+
+	addresses := make([]string, 0, len(ips))
+	byAddress := make(map[string]string, len(ips))
+	for _, ip := range ips {
+		// Only check addresses reachable over the configured stack
+		if (cfg.network == "tcp4" && ip.IP.To4() == nil) || (cfg.network == "tcp6" && ip.IP.To4() != nil) {
+			continue
+		}
+		address := net.JoinHostPort(ip.String(), port)
+		addresses = append(addresses, address)
+		byAddress[address] = ip.String()
+	}
+
+	batch, err := ExpiredBatchContext(ctx, addresses, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]BatchResult, len(batch))
+	for address, r := range batch {
+		results[byAddress[address]] = r
+	}
+	return results, nil
+}
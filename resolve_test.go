@@ -0,0 +1,255 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test fetching the certificate chain from every address a host resolves to
+func TestFetchChainAllIPs(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Hostname", func(t *testing.T) {
+		results, err := FetchChainAllIPs("localhost", "9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling FetchChainAllIPs - %s", err)
+		}
+		r, ok := results["127.0.0.1"]
+		if !ok {
+			t.Fatalf("Expected a result for 127.0.0.1, got %+v", results)
+		}
+		if r.Err != nil || r.Expired || len(r.Chain) == 0 {
+			t.Errorf("Unexpected result for valid certificate - %+v", r)
+		}
+	})
+
+	t.Run("IPv4Only", func(t *testing.T) {
+		results, err := FetchChainAllIPs("localhost", "9000", WithNetwork("tcp4"))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling FetchChainAllIPs - %s", err)
+		}
+		for ip, r := range results {
+			if net.ParseIP(ip).To4() == nil {
+				t.Errorf("Unexpected IPv6 result when limited to tcp4 - %s", ip)
+			}
+			if r.Err != nil {
+				t.Errorf("Unexpected failure for %s - %s", ip, r.Err)
+			}
+		}
+		if len(results) == 0 {
+			t.Errorf("Expected a result for 127.0.0.1, got none")
+		}
+	})
+
+	t.Run("IPLiteral", func(t *testing.T) {
+		results, err := FetchChainAllIPs("127.0.0.1", "9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling FetchChainAllIPs - %s", err)
+		}
+		if len(results) != 1 {
+			t.Errorf("Unexpected number of results, expected 1 got %d", len(results))
+		}
+	})
+
+	t.Run("PartialResults", func(t *testing.T) {
+		results, err := FetchChainAllIPs("127.0.0.1", "418")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling FetchChainAllIPs - %s", err)
+		}
+		if r := results["127.0.0.1"]; r.Err == nil || !r.Expired {
+			t.Errorf("Expected failure result for an unreachable port - %+v", r)
+		}
+	})
+
+	t.Run("InvalidHost", func(t *testing.T) {
+		_, err := FetchChainAllIPs("iamateapot.invalid", "418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid host, err is nil")
+		}
+	})
+}
+
+// fakeResolver returns a resolver which answers every A query with 127.0.0.1 and every other query with no records,
+// without any network access
+func fakeResolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go func() {
+				defer server.Close()
+				for {
+					// Queries over a stream use a two byte length prefix
+					size := make([]byte, 2)
+					if _, err := io.ReadFull(server, size); err != nil {
+						return
+					}
+					query := make([]byte, binary.BigEndian.Uint16(size))
+					if _, err := io.ReadFull(server, query); err != nil {
+						return
+					}
+
+					// Find the end of the question, the name labels followed by the type and class
+					end := 12
+					for query[end] != 0 {
+						end += int(query[end]) + 1
+					}
+					end += 5
+					qtype := binary.BigEndian.Uint16(query[end-4:])
+
+					resp := append([]byte{}, query[:2]...)
+					resp = append(resp, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+					resp = append(resp, query[12:end]...)
+					if qtype == 1 {
+						resp[7] = 1
+						resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+					}
+					_, _ = server.Write(binary.BigEndian.AppendUint16(nil, uint16(len(resp))))
+					_, _ = server.Write(resp)
+				}
+			}()
+			return client, nil
+		},
+	}
+}
+
+// Test resolving hostnames with a custom resolver
+func TestResolver(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("FetchChain", func(t *testing.T) {
+		chain, err := FetchChain("internal.hazexpired.test:9000", WithResolver(fakeResolver()))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain with a custom resolver - %s", err)
+		}
+		if len(chain) == 0 {
+			t.Errorf("Unexpected empty Certificate Chain")
+		}
+	})
+
+	t.Run("FetchChainAllIPs", func(t *testing.T) {
+		results, err := FetchChainAllIPs("internal.hazexpired.test", "9000", WithResolver(fakeResolver()))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling FetchChainAllIPs with a custom resolver - %s", err)
+		}
+		if r, ok := results["127.0.0.1"]; !ok || r.Err != nil {
+			t.Errorf("Unexpected result for 127.0.0.1 got %+v", results)
+		}
+	})
+
+	t.Run("ResolverFailure", func(t *testing.T) {
+		r := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return nil, fmt.Errorf("no DNS server")
+			},
+		}
+		_, err := FetchChain("internal.hazexpired.test:9000", WithResolver(r))
+		var dialErr *DialError
+		if !errors.As(err, &dialErr) {
+			t.Errorf("Expected DialError when the resolver fails, got %v", err)
+		}
+	})
+}
+
+// Test choosing the SNI hostname for each IP when fetching from all IPs
+func TestServerNameFunc(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	// Start Listener recording the SNI hostname sent by each client
+	var mu sync.Mutex
+	var names []string
+	l, err := startConfigListener(&tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			mu.Lock()
+			names = append(names, hello.ServerName)
+			mu.Unlock()
+			return &chain.cert, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	tt := []struct {
+		name     string
+		fn       func(string) string
+		expected string
+	}{
+		{"Mapping", func(ip string) string { return map[string]string{"127.0.0.1": "backend-a.example.com"}[ip] }, "backend-a.example.com"},
+		{"DefaultsToHostname", func(ip string) string { return "" }, "localhost"},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			mu.Lock()
+			names = nil
+			mu.Unlock()
+
+			results, err := FetchChainAllIPs("localhost", "9000", WithNetwork("tcp4"), WithServerNameFunc(c.fn))
+			if err != nil {
+				t.Fatalf("Unexpected failure when calling FetchChainAllIPs - %s", err)
+			}
+			if r := results["127.0.0.1"]; r.Err != nil {
+				t.Fatalf("Unexpected failure for 127.0.0.1 - %s", r.Err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(names) != 1 || names[0] != c.expected {
+				t.Errorf("Unexpected SNI hostname, expected %s got %v", c.expected, names)
+			}
+		})
+	}
+
+	t.Run("Hostname", func(t *testing.T) {
+		// hostname verification uses the chosen server name
+		certs, err := FetchChain("127.0.0.1:9000", WithVerifyHostname(), WithServerNameFunc(func(ip string) string { return "localhost" }))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if !certs[0].HostnameValid {
+			t.Errorf("Expected the leaf to be valid for the chosen server name")
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package hazexpired
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+// retryable will return true if the error is a transient connection or handshake failure that may succeed when
+// retried. Failures caused by the certificate itself, or a proxy refusing the request, will not change on retry.
+func retryable(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &verifyErr) || errors.Is(err, ErrProxyRejected) {
+		return false
+	}
+	var dialErr *DialError
+	var handshakeErr *HandshakeError
+	return errors.As(err, &dialErr) || errors.As(err, &handshakeErr)
+}
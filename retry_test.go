@@ -0,0 +1,93 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Test retrying transient handshake failures
+func TestRetries(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+	certs, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Logf("Unable to load test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start a listener which drops every other connection before the handshake
+	l, err := net.Listen("tcp", "0.0.0.0:9000")
+	if err != nil {
+		t.Logf("Could not start test listener - %s", err)
+		t.FailNow()
+	}
+	defer l.Close()
+	var accepted int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if atomic.AddInt32(&accepted, 1)%2 == 1 {
+				_ = conn.Close()
+				continue
+			}
+			go func() {
+				defer conn.Close()
+				_ = tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{certs}}).Handshake()
+			}()
+		}
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	t.Run("WithoutRetries", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000")
+		if err == nil {
+			t.Errorf("Expected failure when the connection is dropped, err is nil")
+		}
+		// Consume the successful connection so the next attempt is dropped
+		_, _ = FetchChain("127.0.0.1:9000")
+	})
+
+	t.Run("WithRetries", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithRetries(2, 10*time.Millisecond))
+		if err != nil {
+			t.Errorf("Unexpected failure when retrying a dropped connection - %s", err)
+		}
+	})
+
+	t.Run("ContextDeadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := FetchChainContext(ctx, "iamateapot:418", WithRetries(5, time.Second))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context deadline error when retries exceed the deadline, got %s", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected retries to stop at the context deadline, took %s", time.Since(start))
+		}
+	})
+
+	t.Run("NotRetryable", func(t *testing.T) {
+		if retryable(ErrNoCertificates) {
+			t.Errorf("Unexpected retry of an empty certificate chain")
+		}
+		if retryable(&DialError{Address: "example.com:443", Err: ErrProxyRejected}) {
+			t.Errorf("Unexpected retry of a rejected proxy request")
+		}
+		if !retryable(&DialError{Address: "example.com:443", Err: errors.New("connection refused")}) {
+			t.Errorf("Expected retry of a refused connection")
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package hazexpired
+
+import (
+	"crypto/x509"
+	"slices"
+)
+
+// Role identifies a certificate's position within a certificate chain.
+type Role string
+
+const (
+	// RoleLeaf is the end-entity certificate identifying the remote system
+	RoleLeaf Role = "leaf"
+
+	// RoleIntermediate is a CA certificate issued by another CA, linking the leaf to a root
+	RoleIntermediate Role = "intermediate"
+
+	// RoleRoot is a self-signed CA certificate anchoring the chain
+	RoleRoot Role = "root"
+)
+
+// certificateRole will classify the certificate at the index within a chain. The first certificate is the leaf unless
+// it is a CA, as remote systems present their own certificate first. Otherwise self-signed certificates are roots,
+// including legacy roots which predate the CA flag, remaining CA certificates are intermediates, and anything else is
+// a leaf.
+func certificateRole(cert *x509.Certificate, index int) Role {
+	switch {
+	case index == 0 && !cert.IsCA:
+		return RoleLeaf
+	case selfSigned(cert):
+		return RoleRoot
+	case cert.IsCA:
+		return RoleIntermediate
+	default:
+		return RoleLeaf
+	}
+}
+
+// filterRoles will return the certificates within the chain whose Role is one of the roles, or the chain unchanged
+// when no roles are provided.
+func filterRoles(chain []*CertificateStatus, roles []Role) []*CertificateStatus {
+	if len(roles) == 0 {
+		return chain
+	}
+	var filtered []*CertificateStatus
+	for _, cert := range chain {
+		if slices.Contains(roles, cert.Role) {
+			filtered = append(filtered, cert)
+		}
+	}
+	return filtered
+}
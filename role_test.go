@@ -0,0 +1,168 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test classifying each certificate within a chain as a leaf, intermediate, or root
+func TestRole(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	intermediate, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.IsCA = true
+		leaf.BasicConstraintsValid = true
+		leaf.KeyUsage |= x509.KeyUsageCertSign
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	tt := []struct {
+		name  string
+		cert  *x509.Certificate
+		index int
+		role  Role
+	}{
+		{"Leaf", chain.leaf, 0, RoleLeaf},
+		{"Intermediate", intermediate.leaf, 1, RoleIntermediate},
+		{"Root", chain.ca, 2, RoleRoot},
+		{"FirstIntermediate", intermediate.leaf, 0, RoleIntermediate},
+		{"FirstRoot", chain.ca, 0, RoleRoot},
+		{"MisorderedLeaf", chain.leaf, 1, RoleLeaf},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			if role := certificateRole(c.cert, c.index); role != c.role {
+				t.Errorf("Unexpected role, expected %s got %s", c.role, role)
+			}
+		})
+	}
+
+	t.Run("FetchChain", func(t *testing.T) {
+		certs, err := FetchChain("example.com", WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, intermediate.leaf, chain.ca}}}, nil
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		for i, role := range []Role{RoleLeaf, RoleIntermediate, RoleRoot} {
+			if certs[i].Role != role {
+				t.Errorf("Unexpected role for certificate %d, expected %s got %s", i, role, certs[i].Role)
+			}
+		}
+	})
+
+	t.Run("PEM", func(t *testing.T) {
+		var data []byte
+		for _, cert := range []*x509.Certificate{chain.leaf, chain.ca} {
+			data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+		certs, err := FetchChainFromPEM(data)
+		if err != nil {
+			t.Fatalf("Unexpected failure when parsing PEM data - %s", err)
+		}
+		if certs[0].Role != RoleLeaf || certs[1].Role != RoleRoot {
+			t.Errorf("Unexpected roles from PEM data got %s and %s", certs[0].Role, certs[1].Role)
+		}
+	})
+}
+
+// Test limiting the returned chain to certificates with specific roles
+func TestWithRoles(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	intermediate, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.IsCA = true
+		leaf.BasicConstraintsValid = true
+		leaf.KeyUsage |= x509.KeyUsageCertSign
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, intermediate.leaf, chain.ca}}}, nil
+	})
+
+	tt := []struct {
+		name     string
+		roles    []Role
+		expected []Role
+	}{
+		{"LeafAndRoot", []Role{RoleRoot, RoleLeaf}, []Role{RoleLeaf, RoleRoot}},
+		{"Intermediates", []Role{RoleIntermediate}, []Role{RoleIntermediate}},
+		{"NoRoles", nil, []Role{RoleLeaf, RoleIntermediate, RoleRoot}},
+		{"Unmatched", []Role{"unknown"}, nil},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			certs, err := FetchChain("example.com", dialer, WithRoles(c.roles...))
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+			}
+			if len(certs) != len(c.expected) {
+				t.Fatalf("Unexpected Certificate Chain length, expected %d got %d", len(c.expected), len(certs))
+			}
+			for i, role := range c.expected {
+				if certs[i].Role != role {
+					t.Errorf("Unexpected role for certificate %d, expected %s got %s", i, role, certs[i].Role)
+				}
+			}
+		})
+	}
+}
+
+// Test WithRoles does not change the chain seen by checks relying on the leaf and its issuer
+func TestWithRolesChecks(t *testing.T) {
+	responder := httptest.NewServer(nil)
+	defer responder.Close()
+	chain, err := genChain(time.Now().Add(900*time.Hour), func(leaf *x509.Certificate) {
+		leaf.OCSPServer = []string{responder.URL}
+	})
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	responder.Config.Handler = ocspResponder(chain.ca, chain.caKey, false)
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+
+	t.Run("SplitChain", func(t *testing.T) {
+		leaf, rest, err := SplitChain("localhost:443", dialer, WithRoles(RoleRoot))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling SplitChain - %s", err)
+		}
+		if !leaf.Certificate.Equal(chain.leaf) || len(rest) != 1 {
+			t.Errorf("Unexpected split with WithRoles, expected the leaf and its issuer got %s and %d certificates", leaf.Subject, len(rest))
+		}
+	})
+
+	t.Run("Healthy", func(t *testing.T) {
+		healthy, err := Healthy("localhost:443", dialer, WithRoles(RoleRoot))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling Healthy - %s", err)
+		}
+		if !healthy {
+			t.Errorf("Expected the leaf to be checked regardless of WithRoles")
+		}
+	})
+
+	t.Run("CheckOCSP", func(t *testing.T) {
+		status, err := CheckOCSP("localhost:443", dialer, WithRoles(RoleLeaf))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling CheckOCSP - %s", err)
+		}
+		if status.Status != OCSPGood {
+			t.Errorf("Unexpected OCSP status, expected good got %s", status.Status)
+		}
+	})
+}
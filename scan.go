@@ -0,0 +1,89 @@
+package hazexpired
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// maxScanHostBits limits ScanCIDR to ranges of at most 2^16 addresses, such as an IPv4 /16 or IPv6 /112.
+const maxScanHostBits = 16
+
+// ScanCIDR will fetch the certificate chain from every address within the CIDR range, such as 10.0.0.0/24, on the
+// port, defaulting to 443 when empty, returning a BatchResult per IP for discovering which hosts present expired or
+// expiring certificates. Hosts which cannot be connected to or do not complete a TLS handshake are silently skipped,
+// failures after the handshake, such as a pin mismatch, are recorded within the host's BatchResult. The network and
+// broadcast addresses of IPv4 ranges larger than a /31 are not scanned. Ranges larger than 65536 addresses are
+// rejected. No SNI is sent unless WithServerName is provided, and the number of simultaneous connections can be
+// controlled with WithConcurrency. Unreachable hosts are held for the full timeout, so scanning a /16 with few live
+// hosts takes hours at the defaults of a 3 second timeout and 10 connections, raise WithConcurrency or lower
+// WithTimeout for large ranges.
+func ScanCIDR(cidr string, port string, opts ...Option) (map[string]BatchResult, error) {
+	return ScanCIDRContext(context.Background(), cidr, port, opts...)
+}
+
+// ScanCIDRContext is the same as ScanCIDR but uses the provided context to cancel or set a deadline on fetching the certificate chains.
+// The context's error is returned when it is done before the scan completes.
+func ScanCIDRContext(ctx context.Context, cidr string, port string, opts ...Option) (map[string]BatchResult, error) {
+	ips, err := cidrHosts(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if port == "" {
+		port = defaultPort
+	}
+
+	addresses := make([]string, 0, len(ips))
+	byAddress := make(map[string]string, len(ips))
+	for _, ip := range ips {
+		address := net.JoinHostPort(ip, port)
+		addresses = append(addresses, address)
+		byAddress[address] = ip
+	}
+
+	batch, err := ExpiredBatchContext(ctx, addresses, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// A cancelled scan would otherwise appear as hosts which could not be dialed
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]BatchResult)
+	for address, r := range batch {
+		// Skip hosts which are unreachable or not serving TLS
+		var dialErr *DialError
+		var handshakeErr *HandshakeError
+		if errors.As(r.Err, &dialErr) || errors.As(r.Err, &handshakeErr) {
+			continue
+		}
+		results[byAddress[address]] = r
+	}
+	return results, nil
+}
+
+// cidrHosts will return each host address within the CIDR range, excluding the network and broadcast addresses of
+// IPv4 ranges larger than a /31.
+func cidrHosts(cidr string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CIDR %s - %s", cidr, err)
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > maxScanHostBits {
+		return nil, fmt.Errorf("CIDR %s contains more than %d addresses", cidr, 1<<maxScanHostBits)
+	}
+
+	ips := make([]string, 0, 1<<hostBits)
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		ips = append(ips, addr.String())
+	}
+	if prefix.Addr().Is4() && hostBits > 1 {
+		ips = ips[1 : len(ips)-1]
+	}
+	return ips, nil
+}
@@ -0,0 +1,111 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test enumerating the hosts within a CIDR range
+func TestCIDRHosts(t *testing.T) {
+	tt := []struct {
+		cidr  string
+		hosts []string
+		err   bool
+	}{
+		{"10.0.0.0/30", []string{"10.0.0.1", "10.0.0.2"}, false},
+		{"10.0.0.5/30", []string{"10.0.0.5", "10.0.0.6"}, false},
+		{"10.0.0.0/31", []string{"10.0.0.0", "10.0.0.1"}, false},
+		{"10.0.0.7/32", []string{"10.0.0.7"}, false},
+		{"2001:db8::/126", []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}, false},
+		{"255.255.255.252/30", []string{"255.255.255.253", "255.255.255.254"}, false},
+		{"10.0.0.0/15", nil, true},
+		{"2001:db8::/64", nil, true},
+		{"10.0.0.0", nil, true},
+		{"example.com/24", nil, true},
+	}
+
+	for _, c := range tt {
+		t.Run(c.cidr, func(t *testing.T) {
+			hosts, err := cidrHosts(c.cidr)
+			if c.err && err == nil {
+				t.Errorf("Expected failure when enumerating %s, err is nil", c.cidr)
+			}
+			if !c.err && err != nil {
+				t.Errorf("Unexpected failure when enumerating %s - %s", c.cidr, err)
+			}
+			if !c.err && !reflect.DeepEqual(hosts, c.hosts) {
+				t.Errorf("Unexpected hosts from %s, expected %v got %v", c.cidr, c.hosts, hosts)
+			}
+		})
+	}
+
+	t.Run("Slash16", func(t *testing.T) {
+		hosts, err := cidrHosts("10.1.0.0/16")
+		if err != nil {
+			t.Fatalf("Unexpected failure when enumerating a /16 - %s", err)
+		}
+		if len(hosts) != 65534 {
+			t.Errorf("Unexpected number of hosts in a /16, expected 65534 got %d", len(hosts))
+		}
+	})
+}
+
+// Test scanning a CIDR range for hosts presenting certificates
+func TestScanCIDR(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	// Start Listener
+	l, err := startConfigListener(&tls.Config{Certificates: []tls.Certificate{chain.cert}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("Reachable", func(t *testing.T) {
+		results, err := ScanCIDR("127.0.0.1/32", "9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling ScanCIDR - %s", err)
+		}
+		r, ok := results["127.0.0.1"]
+		if !ok {
+			t.Fatalf("Expected a result for 127.0.0.1, got %+v", results)
+		}
+		if r.Err != nil || r.Expired || len(r.Chain) == 0 {
+			t.Errorf("Unexpected result for valid certificate - %+v", r)
+		}
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		results, err := ScanCIDR("127.0.0.0/30", "1", WithTimeout(time.Second))
+		if err != nil {
+			t.Fatalf("Unexpected failure when calling ScanCIDR - %s", err)
+		}
+		if len(results) != 0 {
+			t.Errorf("Expected unreachable hosts to be skipped, got %+v", results)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results, err := ScanCIDRContext(ctx, "127.0.0.1/32", "9000")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled when the scan is cancelled, got %v with %+v", err, results)
+		}
+	})
+
+	t.Run("InvalidCIDR", func(t *testing.T) {
+		_, err := ScanCIDR("127.0.0.1", "9000")
+		if err == nil {
+			t.Errorf("Expected failure when scanning an invalid CIDR, err is nil")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package hazexpired
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+// sessionTicketWait bounds how long a connection is read after the handshake to receive TLS 1.3 session tickets,
+// which servers send after the handshake completes.
+const sessionTicketWait = 100 * time.Millisecond
+
+// sessionCache is a tls.ClientSessionCache which forgets sessions once the full handshake that established them is
+// older than the maximum age. Sessions renewed through resumption keep the age of the original full handshake, so a
+// rotated certificate is always seen within the maximum age.
+type sessionCache struct {
+	sync.Mutex
+
+	// maxAge is how long after a full handshake its sessions may be resumed
+	maxAge time.Duration
+
+	// entries are the cached sessions keyed by the session cache key provided by crypto/tls
+	entries map[string]sessionEntry
+}
+
+// sessionEntry is a cached session and the time of the full handshake which established it.
+type sessionEntry struct {
+	session *tls.ClientSessionState
+	created time.Time
+}
+
+// Get will return the cached session for the key unless it has outlived the maximum age.
+func (c *sessionCache) Get(key string) (*tls.ClientSessionState, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(entry.created) >= c.maxAge {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.session, true
+}
+
+// Put will cache the session for the key, keeping the original creation time when a resumed session is renewed.
+// Sessions which have outlived the maximum age are removed whenever a new key is cached, so checking many addresses
+// does not grow the cache without bound.
+func (c *sessionCache) Put(key string, cs *tls.ClientSessionState) {
+	c.Lock()
+	defer c.Unlock()
+	if cs == nil {
+		delete(c.entries, key)
+		return
+	}
+	entry, ok := c.entries[key]
+	if !ok {
+		entry.created = time.Now()
+		for k, e := range c.entries {
+			if entry.created.Sub(e.created) >= c.maxAge {
+				delete(c.entries, k)
+			}
+		}
+	}
+	entry.session = cs
+	c.entries[key] = entry
+}
+
+// addressSessionCache scopes a tls.ClientSessionCache to a single dialed address. crypto/tls keys sessions by server
+// name, which is shared by every IP checked with FetchChainAllIPs and every port on a host, so without this one
+// backend could resume another's session and be reported with the other backend's chain.
+type addressSessionCache struct {
+	cache   tls.ClientSessionCache
+	address string
+}
+
+// Get will return the cached session for the key at this address.
+func (c addressSessionCache) Get(key string) (*tls.ClientSessionState, bool) {
+	return c.cache.Get(c.address + " " + key)
+}
+
+// Put will cache the session for the key at this address.
+func (c addressSessionCache) Put(key string, cs *tls.ClientSessionState) {
+	c.cache.Put(c.address+" "+key, cs)
+}
+
+// readSessionTickets will briefly read from the connection so session tickets sent after the handshake are stored.
+func readSessionTickets(c *tls.Conn) {
+	if c.ConnectionState().Version < tls.VersionTLS13 {
+		return
+	}
+	_ = c.SetReadDeadline(time.Now().Add(sessionTicketWait))
+	defer c.SetReadDeadline(time.Time{})
+	_, _ = c.Read(make([]byte, 1))
+}
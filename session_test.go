@@ -0,0 +1,153 @@
+package hazexpired
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+// Test resuming TLS sessions across repeated checks
+func TestSessionResumption(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	rotated, err := genChain(time.Now().Add(1800*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	// Start Listener, serving the rotated certificate once rotate is closed
+	rotate := make(chan struct{})
+	l, err := startConfigListener(&tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			select {
+			case <-rotate:
+				return &rotated.cert, nil
+			default:
+				return &chain.cert, nil
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer l.Close()
+	time.Sleep(30 * time.Millisecond)
+
+	for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		t.Run(tls.VersionName(version), func(t *testing.T) {
+			resumption := WithSessionResumption(time.Hour)
+			for i, want := range []bool{false, true, true} {
+				conn, err := FetchConnectionStatus("127.0.0.1:9000", resumption, WithMaxVersion(version))
+				if err != nil {
+					t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+				}
+				if conn.Resumed != want {
+					t.Errorf("Unexpected resumption on check %d, expected %t got %t", i, want, conn.Resumed)
+				}
+				if len(conn.Chain) != 2 || conn.Chain[0].Certificate.Raw == nil {
+					t.Errorf("Unexpected Certificate Chain on check %d got %+v", i, conn.Chain)
+				}
+			}
+		})
+	}
+
+	t.Run("PerAddress", func(t *testing.T) {
+		// the same server name on another address must not resume the first address's session
+		resumption := WithSessionResumption(time.Hour)
+		for i, c := range []struct {
+			address string
+			resumed bool
+		}{
+			{"127.0.0.1:9000", false},
+			{"127.0.0.2:9000", false},
+			{"127.0.0.1:9000", true},
+		} {
+			conn, err := FetchConnectionStatus(c.address, resumption, WithServerName("localhost"))
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+			}
+			if conn.Resumed != c.resumed {
+				t.Errorf("Unexpected resumption on check %d of %s, expected %t got %t", i, c.address, c.resumed, conn.Resumed)
+			}
+		}
+	})
+
+	t.Run("MaxAge", func(t *testing.T) {
+		resumption := WithSessionResumption(50 * time.Millisecond)
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", resumption)
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.Resumed {
+			t.Errorf("Unexpected resumption on the first check")
+		}
+
+		// Once the session outlives the maximum age a full handshake sees the rotated certificate
+		close(rotate)
+		time.Sleep(100 * time.Millisecond)
+		conn, err = FetchConnectionStatus("127.0.0.1:9000", resumption)
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.Resumed {
+			t.Errorf("Unexpected resumption of a session older than the maximum age")
+		}
+		if !conn.Chain[0].Certificate.Equal(rotated.leaf) {
+			t.Errorf("Expected the rotated certificate after the session expired, got %s", conn.Chain[0])
+		}
+	})
+
+	t.Run("FullHandshake", func(t *testing.T) {
+		client := NewClient(WithSessionResumption(time.Hour))
+		_, err := client.FetchChain("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		conn, err := FetchConnectionStatus("127.0.0.1:9000", append(client.with(nil), WithFullHandshake())...)
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if conn.Resumed {
+			t.Errorf("Unexpected resumption with WithFullHandshake")
+		}
+		conn, err = FetchConnectionStatus("127.0.0.1:9000", client.with(nil)...)
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+		}
+		if !conn.Resumed {
+			t.Errorf("Expected resumption without WithFullHandshake")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			conn, err := FetchConnectionStatus("127.0.0.1:9000")
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Connection Status - %s", err)
+			}
+			if conn.Resumed {
+				t.Errorf("Unexpected resumption without WithSessionResumption")
+			}
+		}
+	})
+}
+
+// Test expired sessions are evicted as new sessions are cached
+func TestSessionCacheEviction(t *testing.T) {
+	cache := &sessionCache{maxAge: 10 * time.Millisecond, entries: make(map[string]sessionEntry)}
+	cache.Put("a.example.com", &tls.ClientSessionState{})
+	cache.Put("b.example.com", &tls.ClientSessionState{})
+	time.Sleep(20 * time.Millisecond)
+	cache.Put("c.example.com", &tls.ClientSessionState{})
+
+	cache.Lock()
+	defer cache.Unlock()
+	if len(cache.entries) != 1 {
+		t.Errorf("Unexpected number of cached sessions, expected expired sessions to be evicted got %d", len(cache.entries))
+	}
+	if _, ok := cache.entries["c.example.com"]; !ok {
+		t.Errorf("Expected the new session to be cached")
+	}
+}
@@ -0,0 +1,160 @@
+package hazexpired
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SOCKS5Auth holds the username and password used to authenticate with a SOCKS5 proxy.
+type SOCKS5Auth struct {
+	// User is the username sent to the proxy
+	User string
+
+	// Password is the password sent to the proxy
+	Password string
+}
+
+// socks5Replies describes the reply codes a SOCKS5 proxy returns when a CONNECT request fails.
+var socks5Replies = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// dialSOCKS5 will connect to the SOCKS5 proxy and issue a CONNECT request for the address, returning a connection
+// tunneled through the proxy to the address. Hostnames are resolved by the proxy.
+func dialSOCKS5(ctx context.Context, d *net.Dialer, proxyAddr string, auth *SOCKS5Auth, address string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid address %s - %s", address, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid port in address %s - %s", address, err)
+	}
+
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not connect to SOCKS5 proxy %s - %w", proxyAddr, err)
+	}
+
+	// Interrupt the SOCKS5 exchange once the context is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	err = socks5Handshake(conn, auth, host, uint16(port))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SOCKS5 proxy %s could not connect to %s - %w", proxyAddr, address, err)
+	}
+	return conn, nil
+}
+
+// socks5Handshake will negotiate authentication with the SOCKS5 proxy and request a connection to the host and port.
+func socks5Handshake(conn net.Conn, auth *SOCKS5Auth, host string, port uint16) error {
+	// Offer no authentication, or username and password when credentials are provided
+	method := byte(0x00)
+	if auth != nil {
+		method = 0x02
+	}
+	_, err := conn.Write([]byte{0x05, 0x01, method})
+	if err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	_, err = io.ReadFull(conn, reply)
+	if err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return fmt.Errorf("Unexpected SOCKS version %d", reply[0])
+	}
+	if reply[1] != method {
+		return fmt.Errorf("%w, no acceptable authentication method", ErrProxyRejected)
+	}
+
+	if auth != nil {
+		if len(auth.User) > 255 || len(auth.Password) > 255 {
+			return fmt.Errorf("SOCKS5 username and password must be at most 255 bytes")
+		}
+		req := []byte{0x01, byte(len(auth.User))}
+		req = append(req, auth.User...)
+		req = append(req, byte(len(auth.Password)))
+		req = append(req, auth.Password...)
+		_, err = conn.Write(req)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadFull(conn, reply)
+		if err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return fmt.Errorf("%w, authentication failed", ErrProxyRejected)
+		}
+	}
+
+	// Request a connection, sending IP literals as addresses and hostnames for the proxy to resolve
+	req := []byte{0x05, 0x01, 0x00}
+	ip := net.ParseIP(host)
+	switch {
+	case ip != nil && ip.To4() != nil:
+		req = append(append(req, 0x01), ip.To4()...)
+	case ip != nil:
+		req = append(append(req, 0x04), ip.To16()...)
+	default:
+		if len(host) > 255 {
+			return fmt.Errorf("Hostname %s is too long for SOCKS5", host)
+		}
+		req = append(append(req, 0x03, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, port)
+	_, err = conn.Write(req)
+	if err != nil {
+		return err
+	}
+
+	// Read the reply header, then skip the bound address and port
+	header := make([]byte, 4)
+	_, err = io.ReadFull(conn, header)
+	if err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		reason, ok := socks5Replies[header[1]]
+		if !ok {
+			reason = fmt.Sprintf("reply code %d", header[1])
+		}
+		return fmt.Errorf("%w - %s", ErrProxyRejected, reason)
+	}
+	var skip int
+	switch header[3] {
+	case 0x01:
+		skip = net.IPv4len + 2
+	case 0x04:
+		skip = net.IPv6len + 2
+	case 0x03:
+		size := make([]byte, 1)
+		_, err = io.ReadFull(conn, size)
+		if err != nil {
+			return err
+		}
+		skip = int(size[0]) + 2
+	default:
+		return fmt.Errorf("Unexpected SOCKS5 address type %d", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip))
+	return err
+}
@@ -0,0 +1,180 @@
+package hazexpired
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startSOCKS5Proxy will start a SOCKS5 proxy which requires the provided username and password
+func startSOCKS5Proxy(user, pass string) (net.Listener, error) {
+	l, err := net.Listen("tcp", "0.0.0.0:9002")
+	if err != nil {
+		return nil, fmt.Errorf("Could not start test SOCKS5 proxy - %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				// Require username and password authentication
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				methods := make([]byte, header[1])
+				if _, err := io.ReadFull(conn, methods); err != nil {
+					return
+				}
+				if methods[0] != 0x02 {
+					_, _ = conn.Write([]byte{0x05, 0xFF})
+					return
+				}
+				_, _ = conn.Write([]byte{0x05, 0x02})
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				u := make([]byte, header[1])
+				if _, err := io.ReadFull(conn, u); err != nil {
+					return
+				}
+				size := make([]byte, 1)
+				if _, err := io.ReadFull(conn, size); err != nil {
+					return
+				}
+				p := make([]byte, size[0])
+				if _, err := io.ReadFull(conn, p); err != nil {
+					return
+				}
+				if string(u) != user || string(p) != pass {
+					_, _ = conn.Write([]byte{0x01, 0x01})
+					return
+				}
+				_, _ = conn.Write([]byte{0x01, 0x00})
+
+				// Read the CONNECT request
+				req := make([]byte, 4)
+				if _, err := io.ReadFull(conn, req); err != nil {
+					return
+				}
+				var host string
+				switch req[3] {
+				case 0x01:
+					ip := make([]byte, net.IPv4len)
+					if _, err := io.ReadFull(conn, ip); err != nil {
+						return
+					}
+					host = net.IP(ip).String()
+				case 0x03:
+					if _, err := io.ReadFull(conn, size); err != nil {
+						return
+					}
+					name := make([]byte, size[0])
+					if _, err := io.ReadFull(conn, name); err != nil {
+						return
+					}
+					host = string(name)
+				default:
+					_, _ = conn.Write([]byte{0x05, 0x08, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				port := make([]byte, 2)
+				if _, err := io.ReadFull(conn, port); err != nil {
+					return
+				}
+				target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+				if err != nil {
+					_, _ = conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				_, _ = conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 127, 0, 0, 1, 0, 0})
+				go func() {
+					_, _ = io.Copy(target, conn)
+				}()
+				_, _ = io.Copy(conn, target)
+			}()
+		}
+	}()
+
+	return l, nil
+}
+
+// Test fetching certificates through a SOCKS5 proxy
+func TestSOCKS5(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	defer l.Close()
+
+	// Start SOCKS5 Proxy requiring user:pass credentials
+	p, err := startSOCKS5Proxy("user", "pass")
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	defer p.Close()
+	time.Sleep(30 * time.Millisecond)
+
+	t.Run("Authenticated", func(t *testing.T) {
+		chain, err := FetchChain("127.0.0.1:9000", WithSOCKS5("127.0.0.1:9002", &SOCKS5Auth{User: "user", Password: "pass"}))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain through SOCKS5 proxy - %s", err)
+		}
+		if len(chain) == 0 {
+			t.Errorf("Unexpected empty Certificate Chain returned through SOCKS5 proxy")
+		}
+	})
+
+	t.Run("Hostname", func(t *testing.T) {
+		_, err := FetchChain("localhost:9000", WithSOCKS5("127.0.0.1:9002", &SOCKS5Auth{User: "user", Password: "pass"}))
+		if err != nil {
+			t.Errorf("Unexpected failure when fetching Certificate Chain by hostname through SOCKS5 proxy - %s", err)
+		}
+	})
+
+	t.Run("BadCredentials", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithSOCKS5("127.0.0.1:9002", &SOCKS5Auth{User: "user", Password: "wrong"}))
+		if !errors.Is(err, ErrProxyRejected) {
+			t.Errorf("Expected ErrProxyRejected when SOCKS5 credentials are wrong, got %s", err)
+		}
+	})
+
+	t.Run("NoCredentials", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithSOCKS5("127.0.0.1:9002", nil))
+		if !errors.Is(err, ErrProxyRejected) {
+			t.Errorf("Expected ErrProxyRejected when SOCKS5 credentials are missing, got %s", err)
+		}
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9003", WithSOCKS5("127.0.0.1:9002", &SOCKS5Auth{User: "user", Password: "pass"}))
+		if !errors.Is(err, ErrProxyRejected) {
+			t.Errorf("Expected ErrProxyRejected when SOCKS5 proxy cannot reach the target, got %s", err)
+		}
+		var dialErr *DialError
+		if !errors.As(err, &dialErr) {
+			t.Errorf("Expected DialError when SOCKS5 proxy refuses the connection, got %T", err)
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package hazexpired
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// startTLSNegotiators maps each supported StartTLS protocol to the function that upgrades a plaintext connection.
+var startTLSNegotiators = map[string]func(conn net.Conn) error{
+	"smtp":     startTLSSMTP,
+	"imap":     startTLSIMAP,
+	"pop3":     startTLSPOP3,
+	"ftp":      startTLSFTP,
+	"postgres": startTLSPostgres,
+	"mysql":    startTLSMySQL,
+}
+
+// FetchChainStartTLS will fetch the certificate chain of a remote system that upgrades a plaintext connection to TLS
+// via StartTLS, such as a mail submission server. Supported protocols are: smtp, imap, pop3, ftp, postgres, mysql.
+func FetchChainStartTLS(address, protocol string, opts ...Option) ([]*CertificateStatus, error) {
+	return FetchChainStartTLSContext(context.Background(), address, protocol, opts...)
+}
+
+// FetchChainStartTLSContext is the same as FetchChainStartTLS but uses the provided context to cancel or set a deadline on the connection and TLS handshake.
+func FetchChainStartTLSContext(ctx context.Context, address, protocol string, opts ...Option) ([]*CertificateStatus, error) {
+	return FetchChainContext(ctx, address, append(append([]Option(nil), opts...), WithStartTLS(protocol))...)
+}
+
+// startTLS will perform the StartTLS negotiation for the specified protocol, leaving the connection ready for a TLS handshake.
+func startTLS(conn net.Conn, protocol string) error {
+	negotiate, ok := startTLSNegotiators[strings.ToLower(protocol)]
+	if !ok {
+		return fmt.Errorf("Unsupported StartTLS protocol %s", protocol)
+	}
+	return negotiate(conn)
+}
+
+// startTLSSMTP will negotiate an SMTP STARTTLS upgrade by greeting the server with EHLO and issuing STARTTLS.
+func startTLSSMTP(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+
+	_, _, err := tp.ReadResponse(220)
+	if err != nil {
+		return fmt.Errorf("Unexpected SMTP greeting - %w", err)
+	}
+
+	err = replyCmd(tp, 250, "EHLO localhost")
+	if err != nil {
+		return fmt.Errorf("SMTP EHLO failed - %w", err)
+	}
+
+	err = replyCmd(tp, 220, "STARTTLS")
+	if err != nil {
+		return fmt.Errorf("SMTP STARTTLS refused - %w", err)
+	}
+	return nil
+}
+
+// startTLSIMAP will negotiate an IMAP STARTTLS upgrade by reading the server greeting and issuing a tagged STARTTLS.
+func startTLSIMAP(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+
+	line, err := tp.ReadLine()
+	if err != nil {
+		return fmt.Errorf("Unexpected IMAP greeting - %w", err)
+	}
+	if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("Unexpected IMAP greeting - %s", line)
+	}
+
+	err = tp.PrintfLine("a001 STARTTLS")
+	if err != nil {
+		return fmt.Errorf("IMAP STARTTLS failed - %w", err)
+	}
+	// Skip any untagged responses until the tagged completion
+	for {
+		line, err = tp.ReadLine()
+		if err != nil {
+			return fmt.Errorf("IMAP STARTTLS failed - %w", err)
+		}
+		if strings.HasPrefix(line, "a001 ") {
+			break
+		}
+	}
+	if !strings.HasPrefix(line, "a001 OK") {
+		return fmt.Errorf("IMAP STARTTLS refused - %s", line)
+	}
+	return nil
+}
+
+// startTLSPOP3 will negotiate a POP3 STLS upgrade by reading the server greeting and issuing STLS.
+func startTLSPOP3(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+
+	line, err := tp.ReadLine()
+	if err != nil {
+		return fmt.Errorf("Unexpected POP3 greeting - %w", err)
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("Unexpected POP3 greeting - %s", line)
+	}
+
+	err = tp.PrintfLine("STLS")
+	if err != nil {
+		return fmt.Errorf("POP3 STLS failed - %w", err)
+	}
+	line, err = tp.ReadLine()
+	if err != nil {
+		return fmt.Errorf("POP3 STLS failed - %w", err)
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("POP3 STLS refused - %s", line)
+	}
+	return nil
+}
+
+// startTLSFTP will negotiate an explicit FTPS upgrade by reading the server greeting and issuing AUTH TLS.
+func startTLSFTP(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+
+	_, _, err := tp.ReadResponse(220)
+	if err != nil {
+		return fmt.Errorf("Unexpected FTP greeting - %w", err)
+	}
+
+	err = replyCmd(tp, 234, "AUTH TLS")
+	if err != nil {
+		return fmt.Errorf("FTP AUTH TLS refused - %w", err)
+	}
+	return nil
+}
+
+// postgresSSLRequest is the PostgreSQL SSLRequest message, a length of 8 followed by the SSLRequest code 80877103.
+var postgresSSLRequest = []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}
+
+// startTLSPostgres will negotiate a PostgreSQL TLS upgrade by sending an SSLRequest and reading the single byte reply.
+func startTLSPostgres(conn net.Conn) error {
+	_, err := conn.Write(postgresSSLRequest)
+	if err != nil {
+		return fmt.Errorf("PostgreSQL SSLRequest failed - %w", err)
+	}
+
+	reply := make([]byte, 1)
+	_, err = io.ReadFull(conn, reply)
+	if err != nil {
+		return fmt.Errorf("PostgreSQL SSLRequest failed - %w", err)
+	}
+	switch reply[0] {
+	case 'S':
+		return nil
+	case 'N':
+		return fmt.Errorf("PostgreSQL server does not support TLS")
+	default:
+		return fmt.Errorf("Unexpected PostgreSQL SSLRequest reply %q", reply[0])
+	}
+}
+
+const (
+	// mysqlClientProtocol41 is the MySQL capability flag for the 4.1 protocol
+	mysqlClientProtocol41 = 0x00000200
+
+	// mysqlClientSSL is the MySQL capability flag for switching to TLS
+	mysqlClientSSL = 0x00000800
+
+	// mysqlClientSecureConnection is the MySQL capability flag for 4.1 authentication
+	mysqlClientSecureConnection = 0x00008000
+
+	// mysqlMaxHandshake is the largest initial handshake packet accepted from a MySQL server
+	mysqlMaxHandshake = 1 << 16
+)
+
+// startTLSMySQL will negotiate a MySQL or MariaDB TLS upgrade by reading the server's initial handshake packet and
+// replying with an SSLRequest packet.
+func startTLSMySQL(conn net.Conn) error {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return fmt.Errorf("Unexpected MySQL handshake - %w", err)
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if length == 0 || length > mysqlMaxHandshake {
+		return fmt.Errorf("Unexpected MySQL handshake length %d", length)
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(conn, payload)
+	if err != nil {
+		return fmt.Errorf("Unexpected MySQL handshake - %w", err)
+	}
+
+	// An error packet is a 0xff marker, a 2 byte code, and an optional SQL state before the message
+	if payload[0] == 0xff {
+		msg := ""
+		if len(payload) > 3 {
+			msg = strings.TrimPrefix(string(payload[3:]), "#")
+		}
+		return fmt.Errorf("MySQL server refused the connection - %s", msg)
+	}
+	if payload[0] != 10 {
+		return fmt.Errorf("Unsupported MySQL protocol version %d", payload[0])
+	}
+
+	// Skip the server version, connection id, first part of the auth data, and filler to the capability flags
+	end := bytes.IndexByte(payload[1:], 0)
+	if end < 0 {
+		return fmt.Errorf("Unexpected MySQL handshake, server version is not terminated")
+	}
+	pos := 1 + end + 1 + 4 + 8 + 1
+	if len(payload) < pos+2 {
+		return fmt.Errorf("Unexpected MySQL handshake, capability flags are missing")
+	}
+	if binary.LittleEndian.Uint16(payload[pos:])&mysqlClientSSL == 0 {
+		return fmt.Errorf("MySQL server does not support TLS")
+	}
+
+	// Reply with an SSLRequest, capability flags, max packet size, character set, and 23 reserved bytes
+	req := make([]byte, 4+32)
+	req[0] = 32
+	req[3] = header[3] + 1
+	binary.LittleEndian.PutUint32(req[4:], mysqlClientProtocol41|mysqlClientSSL|mysqlClientSecureConnection)
+	binary.LittleEndian.PutUint32(req[8:], 1<<24)
+	req[12] = 33
+	_, err = conn.Write(req)
+	if err != nil {
+		return fmt.Errorf("MySQL SSLRequest failed - %w", err)
+	}
+	return nil
+}
+
+// replyCmd will send an SMTP or FTP style command and read the numeric reply, returning an error if the reply code is not expected.
+func replyCmd(tp *textproto.Conn, code int, cmd string) error {
+	id, err := tp.Cmd("%s", cmd)
+	if err != nil {
+		return err
+	}
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+	_, _, err = tp.ReadResponse(code)
+	return err
+}
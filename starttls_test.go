@@ -0,0 +1,413 @@
+package hazexpired
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startStartTLSListener will start a plaintext listener that runs the provided negotiation before upgrading each connection to TLS
+func startStartTLSListener(cert, key []byte, negotiate func(tp *textproto.Conn) error) (net.Listener, error) {
+	certs, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("Could not start test listener - %s", err)
+	}
+	conf := &tls.Config{Certificates: []tls.Certificate{certs}}
+
+	l, err := net.Listen("tcp", "0.0.0.0:9000")
+	if err != nil {
+		return nil, fmt.Errorf("Could not start test listener - %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				tp := textproto.NewConn(conn)
+				tp.R = br
+				err := negotiate(tp)
+				if err != nil {
+					return
+				}
+				// Clients may send the TLS ClientHello without waiting, so hand over any buffered data
+				_ = tls.Server(&bufferedConn{Conn: conn, r: br}, conf).Handshake()
+			}()
+		}
+	}()
+
+	return l, nil
+}
+
+// smtpServer is a minimal SMTP server negotiation that accepts STARTTLS
+func smtpServer(tp *textproto.Conn) error {
+	_ = tp.PrintfLine("220 localhost ESMTP")
+	if _, err := tp.ReadLine(); err != nil {
+		return err
+	}
+	_ = tp.PrintfLine("250-localhost")
+	_ = tp.PrintfLine("250 STARTTLS")
+	line, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	if line != "STARTTLS" {
+		_ = tp.PrintfLine("502 command not implemented")
+		return fmt.Errorf("unexpected command %s", line)
+	}
+	return tp.PrintfLine("220 ready to start TLS")
+}
+
+// imapServer is a minimal IMAP server negotiation that accepts STARTTLS
+func imapServer(tp *textproto.Conn) error {
+	_ = tp.PrintfLine("* OK IMAP4rev1 ready")
+	line, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	tag, cmd, _ := strings.Cut(line, " ")
+	if cmd != "STARTTLS" {
+		_ = tp.PrintfLine("%s BAD unknown command", tag)
+		return fmt.Errorf("unexpected command %s", line)
+	}
+	_ = tp.PrintfLine("* CAPABILITY IMAP4rev1")
+	return tp.PrintfLine("%s OK Begin TLS negotiation now", tag)
+}
+
+// pop3Server is a minimal POP3 server negotiation that accepts STLS
+func pop3Server(tp *textproto.Conn) error {
+	_ = tp.PrintfLine("+OK POP3 ready")
+	line, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	if line != "STLS" {
+		_ = tp.PrintfLine("-ERR unknown command")
+		return fmt.Errorf("unexpected command %s", line)
+	}
+	return tp.PrintfLine("+OK Begin TLS negotiation")
+}
+
+// ftpServer is a minimal FTP server negotiation that accepts AUTH TLS
+func ftpServer(tp *textproto.Conn) error {
+	_ = tp.PrintfLine("220-Welcome")
+	_ = tp.PrintfLine("220 FTP ready")
+	line, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	if line != "AUTH TLS" {
+		_ = tp.PrintfLine("502 command not implemented")
+		return fmt.Errorf("unexpected command %s", line)
+	}
+	return tp.PrintfLine("234 AUTH TLS successful")
+}
+
+// postgresServer returns a minimal PostgreSQL server negotiation that reads an SSLRequest and replies with the provided byte
+func postgresServer(reply byte) func(tp *textproto.Conn) error {
+	return func(tp *textproto.Conn) error {
+		req := make([]byte, 8)
+		if _, err := io.ReadFull(tp.R, req); err != nil {
+			return err
+		}
+		if !bytes.Equal(req, []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}) {
+			return fmt.Errorf("unexpected startup message %x", req)
+		}
+		_ = tp.W.WriteByte(reply)
+		if err := tp.W.Flush(); err != nil {
+			return err
+		}
+		if reply != 'S' {
+			return fmt.Errorf("TLS not supported")
+		}
+		return nil
+	}
+}
+
+// mysqlServer returns a minimal MySQL server negotiation advertising the provided capability flags, which reads an
+// SSLRequest when TLS is advertised
+func mysqlServer(caps uint16) func(tp *textproto.Conn) error {
+	return func(tp *textproto.Conn) error {
+		payload := []byte{10}
+		payload = append(payload, "8.0.36\x00"...)
+		payload = append(payload, 1, 0, 0, 0)
+		payload = append(payload, "abcdefgh"...)
+		payload = append(payload, 0)
+		payload = binary.LittleEndian.AppendUint16(payload, caps)
+		payload = append(payload, 33, 2, 0, 0, 0, 21)
+		payload = append(payload, make([]byte, 10)...)
+		_, _ = tp.W.Write([]byte{byte(len(payload)), 0, 0, 0})
+		_, _ = tp.W.Write(payload)
+		if err := tp.W.Flush(); err != nil {
+			return err
+		}
+		if caps&0x0800 == 0 {
+			return fmt.Errorf("TLS not supported")
+		}
+
+		req := make([]byte, 36)
+		if _, err := io.ReadFull(tp.R, req); err != nil {
+			return err
+		}
+		if req[0] != 32 || req[3] != 1 || binary.LittleEndian.Uint32(req[4:])&0x0800 == 0 {
+			return fmt.Errorf("unexpected SSLRequest %x", req)
+		}
+		return nil
+	}
+}
+
+// Test fetching certificates from servers which require StartTLS
+func TestStartTLS(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	t.Run("SMTP", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, smtpServer)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		chain, err := FetchChainStartTLS("127.0.0.1:9000", "smtp")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over SMTP StartTLS - %s", err)
+		}
+		if len(chain) == 0 || chain[0].ExpiredNow {
+			t.Errorf("Unexpected Certificate Chain returned over SMTP StartTLS - %+v", chain)
+		}
+	})
+
+	t.Run("SMTPRefused", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, func(tp *textproto.Conn) error {
+			_ = tp.PrintfLine("554 no service")
+			return fmt.Errorf("refused")
+		})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "smtp")
+		if err == nil {
+			t.Errorf("Expected failure when SMTP server refuses the connection, err is nil")
+		}
+	})
+
+	t.Run("IMAP", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, imapServer)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		chain, err := FetchChainStartTLS("127.0.0.1:9000", "imap")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over IMAP StartTLS - %s", err)
+		}
+		if len(chain) == 0 || chain[0].ExpiredNow {
+			t.Errorf("Unexpected Certificate Chain returned over IMAP StartTLS - %+v", chain)
+		}
+	})
+
+	t.Run("IMAPRefused", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, func(tp *textproto.Conn) error {
+			_ = tp.PrintfLine("* OK IMAP4rev1 ready")
+			line, _ := tp.ReadLine()
+			tag, _, _ := strings.Cut(line, " ")
+			_ = tp.PrintfLine("%s NO STARTTLS unavailable", tag)
+			return fmt.Errorf("refused")
+		})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "imap")
+		if err == nil {
+			t.Errorf("Expected failure when IMAP server refuses STARTTLS, err is nil")
+		}
+	})
+
+	t.Run("POP3", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, pop3Server)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		chain, err := FetchChainStartTLS("127.0.0.1:9000", "pop3")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over POP3 StartTLS - %s", err)
+		}
+		if len(chain) == 0 || chain[0].ExpiredNow {
+			t.Errorf("Unexpected Certificate Chain returned over POP3 StartTLS - %+v", chain)
+		}
+	})
+
+	t.Run("POP3Refused", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, func(tp *textproto.Conn) error {
+			_ = tp.PrintfLine("+OK POP3 ready")
+			_, _ = tp.ReadLine()
+			_ = tp.PrintfLine("-ERR STLS unavailable")
+			return fmt.Errorf("refused")
+		})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "pop3")
+		if err == nil {
+			t.Errorf("Expected failure when POP3 server refuses STLS, err is nil")
+		}
+	})
+
+	t.Run("FTP", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, ftpServer)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		chain, err := FetchChainStartTLS("127.0.0.1:9000", "ftp")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over FTP AUTH TLS - %s", err)
+		}
+		if len(chain) == 0 || chain[0].ExpiredNow {
+			t.Errorf("Unexpected Certificate Chain returned over FTP AUTH TLS - %+v", chain)
+		}
+	})
+
+	t.Run("FTPRefused", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, func(tp *textproto.Conn) error {
+			_ = tp.PrintfLine("220 FTP ready")
+			_, _ = tp.ReadLine()
+			_ = tp.PrintfLine("534 TLS not available")
+			return fmt.Errorf("refused")
+		})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "ftp")
+		if err == nil || !strings.Contains(err.Error(), "AUTH TLS refused") {
+			t.Errorf("Expected AUTH TLS refused failure when FTP server refuses AUTH TLS, got %v", err)
+		}
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, postgresServer('S'))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		chain, err := FetchChainStartTLS("127.0.0.1:9000", "postgres")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over PostgreSQL SSLRequest - %s", err)
+		}
+		if len(chain) == 0 || chain[0].ExpiredNow {
+			t.Errorf("Unexpected Certificate Chain returned over PostgreSQL SSLRequest - %+v", chain)
+		}
+	})
+
+	t.Run("PostgresNoTLS", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, postgresServer('N'))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "postgres")
+		if err == nil || !strings.Contains(err.Error(), "does not support TLS") {
+			t.Errorf("Expected failure when PostgreSQL server does not support TLS, got %v", err)
+		}
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, mysqlServer(0xffff))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		chain, err := FetchChainStartTLS("127.0.0.1:9000", "mysql")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain over MySQL SSLRequest - %s", err)
+		}
+		if len(chain) == 0 || chain[0].ExpiredNow {
+			t.Errorf("Unexpected Certificate Chain returned over MySQL SSLRequest - %+v", chain)
+		}
+	})
+
+	t.Run("MySQLNoTLS", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, mysqlServer(0xffff&^0x0800))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "mysql")
+		if err == nil || !strings.Contains(err.Error(), "does not support TLS") {
+			t.Errorf("Expected failure when MySQL server does not support TLS, got %v", err)
+		}
+	})
+
+	t.Run("MySQLRefused", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, func(tp *textproto.Conn) error {
+			msg := "Host is not allowed to connect"
+			_, _ = tp.W.Write([]byte{byte(3 + len(msg)), 0, 0, 0, 0xff, 0x6a, 0x04})
+			_, _ = tp.W.WriteString(msg)
+			_ = tp.W.Flush()
+			return fmt.Errorf("refused")
+		})
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "mysql")
+		if err == nil || !strings.Contains(err.Error(), "Host is not allowed") {
+			t.Errorf("Expected failure when MySQL server refuses the connection, got %v", err)
+		}
+	})
+
+	t.Run("UnsupportedProtocol", func(t *testing.T) {
+		l, err := startStartTLSListener(cert, key, smtpServer)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer l.Close()
+
+		_, err = FetchChainStartTLS("127.0.0.1:9000", "gopher")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an unsupported protocol, err is nil")
+		}
+	})
+
+	t.Run("OptionsUnmodified", func(t *testing.T) {
+		opts := make([]Option, 1, 2)
+		opts[0] = WithTimeout(time.Second)
+		_, _ = FetchChainStartTLS("127.0.0.1:1", "smtp", opts...)
+		if opts[:2][1] != nil {
+			t.Errorf("Unexpected write into the spare capacity of the caller's options")
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package hazexpired
+
+import (
+	"context"
+	"iter"
+)
+
+// StreamChain will fetch a remote system's certificate chain and yield a CertificateStatus for each certificate, leaf
+// first, building each only as it is consumed. This suits large scans where statuses are processed and discarded
+// rather than retained. A failure to fetch the chain is yielded once as a nil CertificateStatus and the error.
+//
+//	for cert, err := range hazexpired.StreamChain("example.com:443") {
+//		if err != nil {
+//			// do something
+//		}
+//		fmt.Println(cert)
+//	}
+func StreamChain(address string, opts ...Option) iter.Seq2[*CertificateStatus, error] {
+	return StreamChainContext(context.Background(), address, opts...)
+}
+
+// StreamChainContext is the same as StreamChain but uses the provided context to cancel or set a deadline on the connection and TLS handshake.
+func StreamChainContext(ctx context.Context, address string, opts ...Option) iter.Seq2[*CertificateStatus, error] {
+	return func(yield func(*CertificateStatus, error) bool) {
+		cfg := newConfig(opts...)
+		state, err := cfg.fetchState(ctx, address)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		now := cfg.now()
+		for i := range state.PeerCertificates {
+			if !yield(cfg.newChainStatus(state.PeerCertificates, i, address, now), nil) {
+				return
+			}
+		}
+		if cfg.fetchIssuers {
+			for _, status := range cfg.fetchMissingIssuers(ctx, state.PeerCertificates) {
+				if !yield(status, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test streaming the certificates within a chain one at a time
+func TestStreamChain(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := func(certs ...*x509.Certificate) Option {
+		return WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: certs}}, nil
+		})
+	}
+
+	t.Run("Chain", func(t *testing.T) {
+		var got []*x509.Certificate
+		for cert, err := range StreamChain("example.com", dialer(chain.leaf, chain.ca)) {
+			if err != nil {
+				t.Fatalf("Unexpected failure when streaming Certificate Chain - %s", err)
+			}
+			if cert.Source != "example.com" {
+				t.Errorf("Unexpected Source got %s", cert.Source)
+			}
+			got = append(got, cert.Certificate)
+		}
+		if len(got) != 2 || got[0] != chain.leaf || got[1] != chain.ca {
+			t.Errorf("Unexpected certificates streamed got %+v", got)
+		}
+	})
+
+	t.Run("MatchesFetchChain", func(t *testing.T) {
+		certs, err := FetchChain("example.com", dialer(chain.leaf, chain.ca))
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		i := 0
+		for cert := range StreamChain("example.com", dialer(chain.leaf, chain.ca)) {
+			if cert.Role != certs[i].Role || cert.ExpiresInDays != certs[i].ExpiresInDays || cert.Fingerprint != certs[i].Fingerprint {
+				t.Errorf("Unexpected streamed certificate %d, expected %+v got %+v", i, certs[i], cert)
+			}
+			i++
+		}
+	})
+
+	t.Run("Break", func(t *testing.T) {
+		count := 0
+		for range StreamChain("example.com", dialer(chain.leaf, chain.ca)) {
+			count++
+			break
+		}
+		if count != 1 {
+			t.Errorf("Unexpected number of certificates after break, expected 1 got %d", count)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		count := 0
+		for cert, err := range StreamChain("example.com", dialer()) {
+			count++
+			if cert != nil || !errors.Is(err, ErrNoCertificates) {
+				t.Errorf("Expected ErrNoCertificates from an empty chain, got %v and %v", cert, err)
+			}
+		}
+		if count != 1 {
+			t.Errorf("Unexpected number of results from a failed fetch, expected 1 got %d", count)
+		}
+	})
+}
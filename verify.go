@@ -0,0 +1,73 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// VerifiedChain will fetch a remote system's certificate chain and verify that it builds to a trusted root and is
+// valid for the remote system's hostname. Trusted roots can be provided with WithRootCAs, otherwise the system roots
+// are used. When the chain is not trusted, false is returned along with the verification error, which can be
+// inspected with errors.As for types such as x509.UnknownAuthorityError or x509.HostnameError.
+func VerifiedChain(address string, opts ...Option) (bool, error) {
+	return VerifiedChainContext(context.Background(), address, opts...)
+}
+
+// VerifiedChainContext is the same as VerifiedChain but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func VerifiedChainContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	cfg := newConfig(opts...)
+	state, err := cfg.fetchState(ctx, address)
+	if err != nil {
+		return false, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	err = verifyChain(state.PeerCertificates, cfg.hostname(address), cfg.rootCAs, cfg.now())
+	if err != nil {
+		return false, fmt.Errorf("Certificate chain from %s is not trusted - %w", address, err)
+	}
+	return true, nil
+}
+
+// verifyChain will verify that the leaf certificate builds to a trusted root using the remaining certificates as
+// intermediates. When roots is nil the system roots are used, and when dnsName is empty the hostname is not checked.
+func verifyChain(certs []*x509.Certificate, dnsName string, roots *x509.CertPool, now time.Time) error {
+	if len(certs) == 0 {
+		return ErrNoCertificates
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
+
+// hostname will return the hostname the remote system's certificate is expected to be valid for, this is the
+// configured server name or the host portion of the address including IP literals. An empty string is returned when
+// hostname checks are skipped.
+func (cfg *config) hostname(address string) string {
+	if cfg.noHostname {
+		return ""
+	}
+	if name := cfg.configuredServerName(address); name != "" {
+		return name
+	}
+	return hostFromAddress(address)
+}
+
+// configuredServerName will return the server name chosen for the address by WithServerNameFunc, falling back to the
+// one set by WithServerName, or an empty string when neither is configured.
+func (cfg *config) configuredServerName(address string) string {
+	if cfg.serverNameFunc != nil {
+		if name := cfg.serverNameFunc(hostFromAddress(address)); name != "" {
+			return name
+		}
+	}
+	return cfg.serverName
+}
@@ -0,0 +1,148 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test verifying a certificate chain against trusted roots
+func TestVerifiedChain(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(cert)
+
+	t.Run("Trusted", func(t *testing.T) {
+		v, err := VerifiedChain("127.0.0.1:9000", WithRootCAs(pool), WithServerName("localhost"))
+		if err != nil {
+			t.Errorf("Unexpected failure when verifying a trusted chain - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when verifying a trusted chain, expected true got %t", v)
+		}
+	})
+
+	t.Run("UnknownAuthority", func(t *testing.T) {
+		v, err := VerifiedChain("127.0.0.1:9000", WithServerName("localhost"))
+		if v {
+			t.Errorf("Unexpected result when verifying an untrusted chain, expected false got %t", v)
+		}
+		var authErr x509.UnknownAuthorityError
+		if !errors.As(err, &authErr) {
+			t.Errorf("Expected UnknownAuthorityError when verifying an untrusted chain, got %s", err)
+		}
+	})
+
+	t.Run("HostnameMismatch", func(t *testing.T) {
+		v, err := VerifiedChain("127.0.0.1:9000", WithRootCAs(pool), WithServerName("example.com"))
+		if v {
+			t.Errorf("Unexpected result when verifying a chain for the wrong hostname, expected false got %t", v)
+		}
+		var hostErr x509.HostnameError
+		if !errors.As(err, &hostErr) {
+			t.Errorf("Expected HostnameError when verifying a chain for the wrong hostname, got %s", err)
+		}
+	})
+
+	t.Run("VerifyError", func(t *testing.T) {
+		chain, err := FetchChain("127.0.0.1:9000", WithRootCAs(pool), WithServerName("localhost"))
+		if err != nil || len(chain) == 0 {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if chain[0].VerifyError != nil {
+			t.Errorf("Unexpected VerifyError on a trusted certificate - %s", chain[0].VerifyError)
+		}
+
+		chain, err = FetchChain("127.0.0.1:9000", WithServerName("localhost"))
+		if err != nil || len(chain) == 0 {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		var authErr x509.UnknownAuthorityError
+		if !errors.As(chain[0].VerifyError, &authErr) {
+			t.Errorf("Expected UnknownAuthorityError on an untrusted certificate, got %s", chain[0].VerifyError)
+		}
+	})
+
+	t.Run("EnforcedVerification", func(t *testing.T) {
+		_, err := FetchChain("127.0.0.1:9000", WithInsecureSkipVerify(false), WithRootCAs(pool), WithServerName("localhost"))
+		if err != nil {
+			t.Errorf("Unexpected failure when fetching a trusted chain with verification enforced - %s", err)
+		}
+
+		_, err = FetchChain("127.0.0.1:9000", WithInsecureSkipVerify(false), WithServerName("localhost"))
+		var handshakeErr *HandshakeError
+		if !errors.As(err, &handshakeErr) {
+			t.Errorf("Expected HandshakeError when fetching an untrusted chain with verification enforced, got %s", err)
+		}
+		var authErr x509.UnknownAuthorityError
+		if !errors.As(err, &authErr) {
+			t.Errorf("Expected UnknownAuthorityError when fetching an untrusted chain with verification enforced, got %s", err)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := VerifiedChain("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test checking the leaf against the hostname without requiring a trusted chain
+func TestVerifyHostname(t *testing.T) {
+	chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+	})
+
+	tt := []struct {
+		name    string
+		address string
+		opts    []Option
+		valid   bool
+	}{
+		{"Matching", "localhost:443", []Option{WithVerifyHostname()}, true},
+		{"Mismatched", "example.com:443", []Option{WithVerifyHostname()}, false},
+		{"ServerName", "example.com:443", []Option{WithVerifyHostname(), WithServerName("localhost")}, true},
+		{"Disabled", "localhost:443", nil, false},
+	}
+	for _, c := range tt {
+		t.Run(c.name, func(t *testing.T) {
+			certs, err := FetchChain(c.address, append(c.opts, dialer)...)
+			if err != nil {
+				t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+			}
+			if certs[0].HostnameValid != c.valid {
+				t.Errorf("Unexpected HostnameValid, expected %t got %t", c.valid, certs[0].HostnameValid)
+			}
+			if certs[1].HostnameValid {
+				t.Errorf("Unexpected HostnameValid for a non-leaf certificate")
+			}
+			// the test CA is not trusted, so the hostname result is independent of verification
+			if certs[0].VerifyError == nil {
+				t.Errorf("Expected VerifyError from an untrusted chain")
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package hazexpired
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WatchEvent describes a change in whether a watched remote system's certificate chain is within its threshold, or a
+// rotation of its leaf certificate.
+type WatchEvent struct {
+	// Address is the watched address
+	Address string
+
+	// Expiring is true when a certificate within the chain expires within the threshold, and false once the
+	// chain has recovered, such as after the certificate is rotated
+	Expiring bool
+
+	// Rotated is true when the leaf certificate differs from the one fetched by the previous successful check
+	Rotated bool
+
+	// Chain is the certificate chain fetched by the check which triggered the event
+	Chain []*CertificateStatus
+}
+
+// WatchFunc is called by a Watcher when a watched address enters or leaves its threshold, or its leaf is rotated.
+type WatchFunc func(event WatchEvent)
+
+// Watcher periodically checks remote systems and calls back when a certificate within the chain enters the window
+// before expiry, again when the chain recovers, and whenever the leaf certificate is rotated. Checks which fail to fetch the chain are skipped and retried on
+// the next poll. A Watcher is safe for concurrent use.
+//
+//	w, err := hazexpired.NewWatcher(time.Hour)
+//	if err != nil {
+//		// do something
+//	}
+//	defer w.Stop()
+//	w.Add("example.com:443", 30*24*time.Hour, func(e hazexpired.WatchEvent) {
+//		log.Printf("%s expiring=%t rotated=%t", e.Address, e.Expiring, e.Rotated)
+//	})
+type Watcher struct {
+	// mu guards starting watches against a concurrent Stop
+	mu sync.Mutex
+
+	// interval is the time between checks of each address
+	interval time.Duration
+
+	// opts are the options used when fetching each certificate chain
+	opts []Option
+
+	// ctx is cancelled when the Watcher is stopped
+	ctx context.Context
+
+	// cancel stops all running watches
+	cancel context.CancelFunc
+
+	// wg tracks running watches so Stop can wait for them to exit
+	wg sync.WaitGroup
+}
+
+// NewWatcher will create a Watcher which checks each added address every interval, the options are used for each
+// fetch. An error is returned when the interval is not positive.
+func NewWatcher(interval time.Duration, opts ...Option) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("Watch interval must be positive, got %s", interval)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Watcher{
+		interval: interval,
+		opts:     append([]Option(nil), opts...),
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Add will start watching the address, checking it immediately and then every interval. The callback is called once
+// when a certificate within the chain expires within the threshold, once more when it no longer does, and each time
+// the leaf certificate changes. Adding an address after the Watcher is stopped has no effect.
+func (w *Watcher) Add(address string, threshold time.Duration, callback WatchFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx.Err() != nil {
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.watch(address, threshold, callback)
+	}()
+}
+
+// Stop will stop all watches and wait for any running checks or callbacks to return.
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	w.cancel()
+	w.mu.Unlock()
+	w.wg.Wait()
+}
+
+// watch will check the address every interval until the Watcher is stopped, calling back on each change.
+func (w *Watcher) watch(address string, threshold time.Duration, callback WatchFunc) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	cfg := newConfig(w.opts...)
+	var expiring bool
+	var leaf string
+	for {
+		chain, err := fetchChain(w.ctx, address, w.opts...)
+		if err == nil && len(chain) > 0 && w.ctx.Err() == nil {
+			now := anyExpiresBefore(chain, cfg.now().Add(threshold))
+			rotated := leaf != "" && chain[0].Fingerprint != leaf
+			leaf = chain[0].Fingerprint
+			if now != expiring || rotated {
+				expiring = now
+				callback(WatchEvent{Address: address, Expiring: expiring, Rotated: rotated, Chain: chain})
+			}
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test watching an address as its certificate enters and leaves the threshold
+func TestWatcher(t *testing.T) {
+	expiring, err := genChain(time.Now().Add(5*24*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+	rotated, err := genChain(time.Now().Add(90*24*time.Hour), nil)
+	if err != nil {
+		t.Fatalf("Unable to generate test certificates - %s", err)
+	}
+
+	// Serve the expiring leaf until it is swapped for the rotated leaf
+	var mu sync.Mutex
+	leaf := expiring.leaf
+	dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}}, nil
+	})
+
+	events := make(chan WatchEvent, 10)
+	w, err := NewWatcher(10*time.Millisecond, dialer)
+	if err != nil {
+		t.Fatalf("Unexpected failure when creating Watcher - %s", err)
+	}
+	w.Add("example.com", 30*24*time.Hour, func(e WatchEvent) {
+		events <- e
+	})
+
+	t.Run("Expiring", func(t *testing.T) {
+		select {
+		case e := <-events:
+			if !e.Expiring || e.Address != "example.com" || len(e.Chain) != 1 {
+				t.Errorf("Unexpected event when certificate entered the threshold got %+v", e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for the certificate to enter the threshold")
+		}
+
+		// Give the Watcher time to poll again, it should not repeat the event
+		time.Sleep(50 * time.Millisecond)
+		select {
+		case e := <-events:
+			t.Errorf("Unexpected repeated event got %+v", e)
+		default:
+		}
+	})
+
+	t.Run("Rotated", func(t *testing.T) {
+		mu.Lock()
+		leaf = rotated.leaf
+		mu.Unlock()
+		select {
+		case e := <-events:
+			if e.Expiring || !e.Rotated || e.Chain[0].Certificate != rotated.leaf {
+				t.Errorf("Unexpected event when certificate was rotated got %+v", e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for the rotated certificate to recover")
+		}
+	})
+
+	t.Run("RotatedValid", func(t *testing.T) {
+		renewed, err := genChain(time.Now().Add(120*24*time.Hour), nil)
+		if err != nil {
+			t.Fatalf("Unable to generate test certificates - %s", err)
+		}
+		mu.Lock()
+		leaf = renewed.leaf
+		mu.Unlock()
+		select {
+		case e := <-events:
+			if e.Expiring || !e.Rotated || e.Chain[0].Certificate != renewed.leaf {
+				t.Errorf("Unexpected event when a valid certificate was rotated got %+v", e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for the rotation event")
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		w.Stop()
+		w.Add("example.com", 30*24*time.Hour, func(e WatchEvent) {
+			t.Errorf("Unexpected callback after the Watcher was stopped got %+v", e)
+		})
+		mu.Lock()
+		leaf = expiring.leaf
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		select {
+		case e := <-events:
+			t.Errorf("Unexpected event after the Watcher was stopped got %+v", e)
+		default:
+		}
+	})
+}
+
+// Test creating a Watcher with an invalid interval
+func TestNewWatcherInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		w, err := NewWatcher(interval)
+		if err == nil || w != nil {
+			t.Errorf("Expected failure when creating a Watcher with interval %s, got %v", interval, err)
+		}
+	}
+}
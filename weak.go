@@ -0,0 +1,85 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+)
+
+// weakSignatureAlgorithms are the deprecated signature algorithms considered weak when WithAllowedSignatureAlgorithms is not provided.
+var weakSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// HasWeakSignature will return true if any certificate within the remote system's certificate chain is signed using
+// a weak signature algorithm. By default MD2, MD5, and SHA-1 based algorithms are considered weak, this can be
+// replaced by an explicit list of acceptable algorithms using WithAllowedSignatureAlgorithms.
+func HasWeakSignature(address string, opts ...Option) (bool, error) {
+	return HasWeakSignatureContext(context.Background(), address, opts...)
+}
+
+// HasWeakSignatureContext is the same as HasWeakSignature but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func HasWeakSignatureContext(ctx context.Context, address string, opts ...Option) (bool, error) {
+	cfg := newConfig(opts...)
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	for _, cert := range chain {
+		if cfg.weakSignature(cert.SignatureAlgorithm) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// weakSignature will return true if the signature algorithm is weak, either by not being within the configured
+// allowed algorithms or, when none are configured, by being a known deprecated algorithm.
+func (cfg *config) weakSignature(alg x509.SignatureAlgorithm) bool {
+	if cfg.allowedSignatureAlgorithms != nil {
+		return !cfg.allowedSignatureAlgorithms[alg]
+	}
+	return weakSignatureAlgorithms[alg]
+}
+
+// HasWeakKey will return true if any certificate within the remote system's certificate chain has an RSA public key
+// smaller than minBits, such as 2048. Other key types are not flagged, their sizes are reported as the KeyBits of
+// each CertificateStatus.
+func HasWeakKey(address string, minBits int, opts ...Option) (bool, error) {
+	return HasWeakKeyContext(context.Background(), address, minBits, opts...)
+}
+
+// HasWeakKeyContext is the same as HasWeakKey but uses the provided context to cancel or set a deadline on fetching the certificate chain.
+func HasWeakKeyContext(ctx context.Context, address string, minBits int, opts ...Option) (bool, error) {
+	chain, err := fetchChain(ctx, address, opts...)
+	if err != nil {
+		return true, fmt.Errorf("Error Fetching Certificate Chain - %w", err)
+	}
+	for _, cert := range chain {
+		if cert.Certificate != nil && cert.Certificate.PublicKeyAlgorithm == x509.RSA && cert.KeyBits < minBits {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// keyBits will return the size of the public key in bits, zero when the key type is not recognized.
+func keyBits(pub any) int {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return len(k) * 8
+	default:
+		return 0
+	}
+}
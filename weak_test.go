@@ -0,0 +1,144 @@
+package hazexpired
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+// Test detection of weak signature algorithms within the chain
+func TestHasWeakSignature(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("DefaultWeakSet", func(t *testing.T) {
+		v, err := HasWeakSignature("127.0.0.1:9000")
+		if err != nil {
+			t.Errorf("Unexpected failure when calling HasWeakSignature - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing HasWeakSignature with a SHA-256 certificate, expected false got %t", v)
+		}
+	})
+
+	t.Run("AllowedAlgorithms", func(t *testing.T) {
+		v, err := HasWeakSignature("127.0.0.1:9000", WithAllowedSignatureAlgorithms(x509.ECDSAWithSHA384))
+		if err != nil {
+			t.Errorf("Unexpected failure when calling HasWeakSignature - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing HasWeakSignature with a disallowed algorithm, expected true got %t", v)
+		}
+	})
+
+	t.Run("DeprecatedAlgorithms", func(t *testing.T) {
+		cfg := newConfig()
+		for _, alg := range []x509.SignatureAlgorithm{x509.MD5WithRSA, x509.SHA1WithRSA, x509.ECDSAWithSHA1} {
+			if !cfg.weakSignature(alg) {
+				t.Errorf("Expected %s to be considered weak", alg)
+			}
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := HasWeakSignature("iamateapot:418")
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}
+
+// Test reporting key sizes and detection of small RSA keys within the chain
+func TestHasWeakKey(t *testing.T) {
+	// Create cert/key pair
+	cert, key, err := genCerts(time.Now().Add(900 * time.Hour))
+	if err != nil {
+		t.Logf("Unable to generate test certificates - %s", err)
+		t.FailNow()
+	}
+
+	// Start Listener
+	l, err := startListener(cert, key)
+	if err != nil {
+		t.Logf("%s", err)
+		t.FailNow()
+	}
+	time.Sleep(30 * time.Millisecond)
+	defer l.Close()
+
+	t.Run("RSAKeyBits", func(t *testing.T) {
+		chain, err := FetchChain("127.0.0.1:9000")
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if chain[0].KeyBits != 4096 {
+			t.Errorf("Unexpected key size, expected 4096 got %d", chain[0].KeyBits)
+		}
+	})
+
+	t.Run("StrongRSA", func(t *testing.T) {
+		v, err := HasWeakKey("127.0.0.1:9000", 2048)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling HasWeakKey - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing HasWeakKey with a 4096 bit key, expected false got %t", v)
+		}
+	})
+
+	t.Run("WeakRSA", func(t *testing.T) {
+		v, err := HasWeakKey("127.0.0.1:9000", 8192)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling HasWeakKey - %s", err)
+		}
+		if !v {
+			t.Errorf("Unexpected result when testing HasWeakKey with a 4096 bit key below the minimum, expected true got %t", v)
+		}
+	})
+
+	t.Run("ECDSA", func(t *testing.T) {
+		chain, err := genChain(time.Now().Add(900*time.Hour), nil)
+		if err != nil {
+			t.Fatalf("Unable to generate test certificates - %s", err)
+		}
+		dialer := WithDialFunc(func(ctx context.Context, network, address string) (Conn, error) {
+			return &fakeConn{state: tls.ConnectionState{PeerCertificates: []*x509.Certificate{chain.leaf, chain.ca}}}, nil
+		})
+		certs, err := FetchChain("example.com", dialer)
+		if err != nil {
+			t.Fatalf("Unexpected failure when fetching Certificate Chain - %s", err)
+		}
+		if certs[0].KeyBits != 256 {
+			t.Errorf("Unexpected key size for a P-256 key, expected 256 got %d", certs[0].KeyBits)
+		}
+		v, err := HasWeakKey("example.com", 2048, dialer)
+		if err != nil {
+			t.Errorf("Unexpected failure when calling HasWeakKey - %s", err)
+		}
+		if v {
+			t.Errorf("Unexpected result when testing HasWeakKey with ECDSA keys, expected false got %t", v)
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := HasWeakKey("iamateapot:418", 2048)
+		if err == nil {
+			t.Errorf("Expected failure when calling with an invalid address, err is nil")
+		}
+	})
+}